@@ -0,0 +1,401 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// aliasEntry is the definition of a single alias. In the aliases file, an
+// alias with no settings other than its package is stored as a plain string,
+// and a bundle with no other settings as a list of packages.
+type aliasEntry struct {
+	// Package is the package path the alias expands to. It is empty for
+	// bundles.
+	Package string `json:"package,omitempty"`
+	// Bundle lists the packages the alias expands to, if it stands for
+	// several packages rather than one.
+	Bundle []string `json:"bundle,omitempty"`
+	// Version is the version get and install use when the argument doesn't
+	// give one, e.g. "v1.2.3".
+	Version string `json:"version,omitempty"`
+	// MinGo is the minimum Go version required to install the package,
+	// e.g. "1.21".
+	MinGo string `json:"minGo,omitempty"`
+	// RawMajor disables the major version handling for the alias, so that a
+	// major version in the argument is passed through verbatim.
+	RawMajor bool `json:"rawMajor,omitempty"`
+	// ExactMatch restricts the alias to arguments that are exactly the
+	// alias name, optionally with a version, rather than any argument it
+	// is a prefix of.
+	ExactMatch bool `json:"exactMatch,omitempty"`
+	// Description is a free-form note describing the alias.
+	Description string `json:"description,omitempty"`
+	// Tags are labels used to group aliases.
+	Tags []string `json:"tags,omitempty"`
+}
+
+// plain reports whether e has no settings other than its package or bundle.
+func (e aliasEntry) plain() bool {
+	return e.Version == "" && e.MinGo == "" && !e.RawMajor && !e.ExactMatch && e.Description == "" && len(e.Tags) == 0
+}
+
+// isBundle reports whether e expands to several packages.
+func (e aliasEntry) isBundle() bool {
+	return len(e.Bundle) > 0
+}
+
+// target describes what e expands to, for display: its package, or the
+// packages of a bundle.
+func (e aliasEntry) target() string {
+	if e.isBundle() {
+		return "[" + strings.Join(e.Bundle, ", ") + "]"
+	}
+	return e.Package
+}
+
+func (e aliasEntry) MarshalJSON() ([]byte, error) {
+	if e.plain() {
+		if e.isBundle() {
+			return json.Marshal(e.Bundle)
+		}
+		return json.Marshal(e.Package)
+	}
+	type plain aliasEntry
+	return json.Marshal(plain(e))
+}
+
+func (e *aliasEntry) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		*e = aliasEntry{}
+		return json.Unmarshal(data, &e.Package)
+	}
+	if len(data) > 0 && data[0] == '[' {
+		*e = aliasEntry{}
+		if err := json.Unmarshal(data, &e.Bundle); err != nil {
+			return err
+		}
+		if len(e.Bundle) == 0 {
+			return errors.New("alias bundle has no packages")
+		}
+		return nil
+	}
+	if len(data) == 0 || data[0] != '{' {
+		return errors.New("alias must be a package string, a list of packages or an object")
+	}
+	type plain aliasEntry
+	var p plain
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	if p.Package == "" && len(p.Bundle) == 0 {
+		return errors.New("alias has no package")
+	}
+	if p.Package != "" && len(p.Bundle) > 0 {
+		return errors.New("alias has both a package and a bundle")
+	}
+	*e = aliasEntry(p)
+	return nil
+}
+
+// splitTags splits a comma-separated list of tags, dropping empty ones.
+func splitTags(s string) []string {
+	var tags []string
+	for _, tag := range strings.Split(s, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+const (
+	aliasesFile    = "aliases.json"
+	defaultProfile = "default"
+)
+
+// aliasesPath returns the path of the aliases file for the active profile.
+func aliasesPath() string {
+	return profilePath(aliasesFile)
+}
+
+// profilePath returns the path of the file with the given name in the config
+// dir for the active profile. Each profile other than the default has its own
+// file, named with the profile before the extension, as in aliases-work.json.
+func profilePath(name string) string {
+	if profile == "" || profile == defaultProfile {
+		return filepath.Join(configDir, name)
+	}
+	ext := filepath.Ext(name)
+	return filepath.Join(configDir, strings.TrimSuffix(name, ext)+"-"+profile+ext)
+}
+
+// listProfiles returns the names of the profiles with an aliases file in the
+// config dir, sorted. The default profile is always included.
+func listProfiles() ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(configDir, "aliases-*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("list profiles: %w", err)
+	}
+	profiles := []string{defaultProfile}
+	for _, m := range matches {
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(m), "aliases-"), ".json")
+		if name != defaultProfile {
+			profiles = append(profiles, name)
+		}
+	}
+	sort.Strings(profiles[1:])
+	return profiles, nil
+}
+
+// loadAliases reads the aliases file at path, usually aliasesPath(). A missing
+// file holds no aliases.
+func loadAliases(path string) (map[string]aliasEntry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return make(map[string]aliasEntry), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open aliases file: %w", err)
+	}
+	defer f.Close()
+
+	aliases, err := decodeAliases(f)
+	if err != nil {
+		return nil, fmt.Errorf("decode aliases file %s: %w", path, err)
+	}
+	return aliases, nil
+}
+
+// importAliases reads aliases from the file at src. If src is "-" or
+// "--stdin", aliases are read from standard input instead.
+func importAliases(src string) (map[string]aliasEntry, error) {
+	if src == "-" || src == "--stdin" {
+		aliases, err := decodeAliases(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("decode standard input: %w", err)
+		}
+		return aliases, nil
+	}
+	f, err := os.Open(src)
+	if err != nil {
+		return nil, fmt.Errorf("open import file: %w", err)
+	}
+	defer f.Close()
+
+	aliases, err := decodeAliases(f)
+	if err != nil {
+		return nil, fmt.Errorf("decode import file %s: %w", src, err)
+	}
+	return aliases, nil
+}
+
+// decodeAliases decodes aliases from r. A leading UTF-8 byte order mark, as
+// left by some Windows editors, is ignored. Syntax errors report the line and
+// column they occurred at.
+func decodeAliases(r io.Reader) (map[string]aliasEntry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if len(bytes.TrimSpace(data)) == 0 {
+		return make(map[string]aliasEntry), nil
+	}
+	if err := checkFormat(data); err != nil {
+		return nil, err
+	}
+
+	// Decode each alias separately, so that errors can name the alias.
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			// The offset is just past the offending character.
+			line, col := position(data, syntaxErr.Offset-1)
+			return nil, fmt.Errorf("line %d, column %d: %v", line, col, err)
+		}
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, errors.New("aliases must be a JSON object")
+		}
+		return nil, err
+	}
+	aliases := make(map[string]aliasEntry, len(raw))
+	for name, msg := range raw {
+		var entry aliasEntry
+		if err := json.Unmarshal(msg, &entry); err != nil {
+			return nil, fmt.Errorf("alias %q: %v", name, err)
+		}
+		aliases[name] = entry
+	}
+	return aliases, nil
+}
+
+// position returns the 1-based line and column of the byte at offset in data.
+func position(data []byte, offset int64) (line, col int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	before := data[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	col = len(before) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
+
+// storeAliases writes aliases to the aliases file at path, usually
+// aliasesPath(). Keys are written in sorted order, so the file diffs cleanly
+// when kept under version control. The aliases added or changed are recorded
+// as modified.
+func storeAliases(path string, aliases map[string]aliasEntry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create config dir: %w", err)
+	}
+	// A file that can't be read has nothing to compare against, so every
+	// alias counts as changed.
+	old, _ := loadAliases(path)
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(aliases); err != nil {
+		return fmt.Errorf("encode aliases file: %w", err)
+	}
+	if err := writeFileAtomic(path, buf.Bytes()); err != nil {
+		return fmt.Errorf("write aliases file: %w", err)
+	}
+	// Modification times are informational, like usage, so failing to
+	// record them doesn't fail the change.
+	_ = recordModified(path, old, aliases)
+	return nil
+}
+
+// starterAliases are the example aliases written by initAliases. JSON has no
+// comments, so the descriptions explain how each is used.
+var starterAliases = map[string]aliasEntry{
+	"mod": {
+		Package:     "golang.org/x/mod",
+		Description: "example: ago get mod/modfile fetches golang.org/x/mod/modfile",
+	},
+	"gopls": {
+		Package:     "golang.org/x/tools/gopls",
+		Description: "example: ago install gopls@latest installs the Go language server",
+		Tags:        []string{"tools"},
+	},
+}
+
+// initAliases creates the aliases file at path with starterAliases. An
+// existing file is only replaced if force is set.
+func initAliases(path string, force bool) error {
+	if _, err := os.Stat(path); err == nil && !force {
+		return fmt.Errorf("%s already exists; use --force to replace it", path)
+	}
+	return storeAliases(path, starterAliases)
+}
+
+// writeFileAtomic writes data to the file at path by writing it to a
+// temporary file in the same directory and renaming it into place, so that
+// the file is never left partially written.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+const lastPackageFile = "last-package"
+
+// recordLastPackage records the last package in args, the arguments of a
+// successful get or install command, for use by lastPackage.
+func recordLastPackage(args []string) error {
+	var pkg string
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			pkg = arg
+		}
+	}
+	if idx := strings.LastIndex(pkg, "@"); idx != -1 {
+		pkg = pkg[:idx]
+	}
+	if pkg == "" {
+		return nil
+	}
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return fmt.Errorf("create config dir: %w", err)
+	}
+	return os.WriteFile(filepath.Join(configDir, lastPackageFile), []byte(pkg+"\n"), 0644)
+}
+
+// lastPackage returns the package most recently fetched by a get or install
+// command.
+func lastPackage() (string, error) {
+	data, err := os.ReadFile(filepath.Join(configDir, lastPackageFile))
+	if os.IsNotExist(err) {
+		return "", errors.New("no package has been fetched with ago get or ago install yet")
+	}
+	if err != nil {
+		return "", fmt.Errorf("read last package: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// envAliases returns the aliases defined by the AGO_ALIASES environment
+// variable, a list of name=package words. Words are split like a shell would,
+// so packages containing spaces can be quoted.
+func envAliases() (map[string]aliasEntry, error) {
+	words, err := splitShellWords(os.Getenv("AGO_ALIASES"))
+	if err != nil {
+		return nil, fmt.Errorf("parse AGO_ALIASES: %w", err)
+	}
+	aliases, err := parseAliasWords(words)
+	if err != nil {
+		return nil, fmt.Errorf("parse AGO_ALIASES: %w", err)
+	}
+	return aliases, nil
+}
+
+// parseAliasWords parses words of the form name=package into aliases.
+func parseAliasWords(words []string) (map[string]aliasEntry, error) {
+	aliases := make(map[string]aliasEntry, len(words))
+	for _, word := range words {
+		name, pkg, ok := strings.Cut(word, "=")
+		if !ok || strings.TrimSpace(name) == "" || pkg == "" {
+			return nil, fmt.Errorf("%q is not of the form name=package", word)
+		}
+		aliases[name] = aliasEntry{Package: pkg}
+	}
+	return aliases, nil
+}
+
+// mergeAliases returns a new map holding the aliases of each of layers, with
+// later layers taking precedence.
+func mergeAliases(layers ...map[string]aliasEntry) map[string]aliasEntry {
+	merged := make(map[string]aliasEntry)
+	for _, layer := range layers {
+		for name, entry := range layer {
+			merged[name] = entry
+		}
+	}
+	return merged
+}
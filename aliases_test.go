@@ -0,0 +1,167 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeAliasesBOM(t *testing.T) {
+	aliases, err := decodeAliases(strings.NewReader("\xef\xbb\xbf{\"foo\": \"github.com/foo/bar\"}\n"))
+	if err != nil {
+		t.Fatalf("decodeAliases with a BOM failed: %v", err)
+	}
+	if got := aliases["foo"].Package; got != "github.com/foo/bar" {
+		t.Errorf("alias foo = %q, want github.com/foo/bar", got)
+	}
+}
+
+func TestDecodeAliasesEmpty(t *testing.T) {
+	for _, data := range []string{"", "\n", "\xef\xbb\xbf", "\xef\xbb\xbf\r\n"} {
+		aliases, err := decodeAliases(strings.NewReader(data))
+		if err != nil {
+			t.Errorf("decodeAliases(%q) failed: %v", data, err)
+			continue
+		}
+		if len(aliases) != 0 {
+			t.Errorf("decodeAliases(%q) = %v, want no aliases", data, aliases)
+		}
+	}
+}
+
+func TestLoadAliasesSyntaxError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aliases.json")
+	data := "\xef\xbb\xbf{\n  \"foo\": \"github.com/foo/bar\",\n  \"bar\" \"x\"\n}\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := loadAliases(path)
+	if err == nil {
+		t.Fatal("loadAliases succeeded, want a syntax error")
+	}
+	// The error names the file and the position, counted without the BOM.
+	for _, want := range []string{path, "line 3, column 9"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("loadAliases error = %q, want it to contain %q", err, want)
+		}
+	}
+}
+
+func TestSplitShellWords(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"  -v\t-x\n", []string{"-v", "-x"}},
+		{`-ldflags "-X main.x=y z"`, []string{"-ldflags", "-X main.x=y z"}},
+		{`-ldflags='-X main.x=y z' -trimpath`, []string{"-ldflags=-X main.x=y z", "-trimpath"}},
+		{`'it''s' "a \"b\" \$c \d"`, []string{"its", `a "b" $c \d`}},
+		{`a\ b c`, []string{"a b", "c"}},
+		{`""`, []string{""}},
+	}
+	for _, tt := range tests {
+		got, err := splitShellWords(tt.in)
+		if err != nil {
+			t.Errorf("splitShellWords(%q) failed: %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitShellWords(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	for _, in := range []string{`"-X main.x`, `'-X main.x`} {
+		if _, err := splitShellWords(in); err == nil {
+			t.Errorf("splitShellWords(%q) succeeded, want an unterminated quote error", in)
+		}
+	}
+}
+
+func TestEnvAliasesQuoted(t *testing.T) {
+	t.Setenv("AGO_ALIASES", `foo=github.com/foo/bar 'spaced=./my dir'`)
+	aliases, err := envAliases()
+	if err != nil {
+		t.Fatalf("envAliases failed: %v", err)
+	}
+	want := map[string]aliasEntry{
+		"foo":    {Package: "github.com/foo/bar"},
+		"spaced": {Package: "./my dir"},
+	}
+	if !reflect.DeepEqual(aliases, want) {
+		t.Errorf("envAliases() = %v, want %v", aliases, want)
+	}
+}
+
+func TestStoreLoadAliases(t *testing.T) {
+	// The aliases file is created along with its directory.
+	path := filepath.Join(t.TempDir(), "profile", "aliases.json")
+	aliases := map[string]aliasEntry{
+		"plain": {Package: "github.com/foo/bar"},
+		"object": {
+			Package:     "github.com/foo/baz/v2",
+			Version:     "v2.1.0",
+			Description: "the baz module",
+			Tags:        []string{"tools", "work"},
+		},
+		"bundle": {Bundle: []string{"golang.org/x/tools/gopls", "honnef.co/go/tools/cmd/staticcheck"}},
+	}
+	if err := storeAliases(path, aliases); err != nil {
+		t.Fatalf("storeAliases failed: %v", err)
+	}
+	got, err := loadAliases(path)
+	if err != nil {
+		t.Fatalf("loadAliases failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, aliases) {
+		t.Errorf("loadAliases() = %v, want %v", got, aliases)
+	}
+
+	// Entries with no other settings are stored in their short forms.
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"plain": "github.com/foo/bar"`,
+		`"bundle": [`,
+		`"version": "v2.1.0"`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("aliases file %s does not contain %s", data, want)
+		}
+	}
+}
+
+func TestLoadAliasesMissing(t *testing.T) {
+	aliases, err := loadAliases(filepath.Join(t.TempDir(), "aliases.json"))
+	if err != nil {
+		t.Fatalf("loadAliases of a missing file failed: %v", err)
+	}
+	if len(aliases) != 0 {
+		t.Errorf("loadAliases of a missing file = %v, want no aliases", aliases)
+	}
+}
+
+func TestProfilePath(t *testing.T) {
+	savedDir, savedProfile := configDir, profile
+	t.Cleanup(func() { configDir, profile = savedDir, savedProfile })
+	configDir = "config"
+	tests := []struct {
+		profile, name, want string
+	}{
+		{"", aliasesFile, filepath.Join("config", "aliases.json")},
+		{defaultProfile, historyFile, filepath.Join("config", "history.jsonl")},
+		{"work", aliasesFile, filepath.Join("config", "aliases-work.json")},
+		{"work", historyFile, filepath.Join("config", "history-work.jsonl")},
+		{"work", usageFile, filepath.Join("config", "usage-work.json")},
+		{"work", installedFile, filepath.Join("config", "installed-work.json")},
+	}
+	for _, tt := range tests {
+		profile = tt.profile
+		if got := profilePath(tt.name); got != tt.want {
+			t.Errorf("profile %q: profilePath(%q) = %q, want %q", tt.profile, tt.name, got, tt.want)
+		}
+	}
+}
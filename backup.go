@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const backupsDir = "backups"
+
+// backupDir returns the directory holding the backups of the active profile.
+// Backups of the default profile are kept in the backups directory itself, and
+// those of other profiles in a subdirectory named after the profile, so that a
+// backup is only ever restored into the profile it was taken of.
+func backupDir() string {
+	if profile == "" || profile == defaultProfile {
+		return filepath.Join(configDir, backupsDir)
+	}
+	return filepath.Join(configDir, backupsDir, profile)
+}
+
+// backupPath returns the path of the backup of the active profile with the
+// given name.
+func backupPath(name string) string {
+	return filepath.Join(backupDir(), name+".json")
+}
+
+// validBackupName reports whether name can be used as a backup name.
+func validBackupName(name string) bool {
+	return name != "" && name != "list" && !strings.ContainsAny(name, `/\`) && !strings.HasPrefix(name, ".")
+}
+
+// backupAliases copies the aliases file of the active profile to a backup
+// with the given name. If name is empty, a timestamp is used. It returns the
+// name of the backup.
+func backupAliases(name string) (string, error) {
+	if name == "" {
+		name = time.Now().Format("20060102-150405")
+	}
+	if !validBackupName(name) {
+		return "", fmt.Errorf("invalid backup name %q", name)
+	}
+	src, err := os.Open(aliasesPath())
+	if os.IsNotExist(err) {
+		return "", errors.New("there are no aliases to back up")
+	}
+	if err != nil {
+		return "", fmt.Errorf("open aliases file: %w", err)
+	}
+	defer src.Close()
+
+	if err := os.MkdirAll(backupDir(), 0755); err != nil {
+		return "", fmt.Errorf("create backups dir: %w", err)
+	}
+	if err := copyToFile(backupPath(name), src); err != nil {
+		return "", fmt.Errorf("write backup: %w", err)
+	}
+	return name, nil
+}
+
+// listBackups returns the names of the backups of the active profile, sorted.
+func listBackups() ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(backupDir(), "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("list backups: %w", err)
+	}
+	names := make([]string, 0, len(matches))
+	for _, m := range matches {
+		names = append(names, strings.TrimSuffix(filepath.Base(m), ".json"))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// restoreAliases replaces the aliases file of the active profile with the
+// backup with the given name.
+func restoreAliases(name string) error {
+	if !validBackupName(name) {
+		return fmt.Errorf("invalid backup name %q", name)
+	}
+	data, err := os.ReadFile(backupPath(name))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("backup %q does not exist", name)
+	}
+	if err != nil {
+		return fmt.Errorf("read backup: %w", err)
+	}
+
+	// Make sure the backup is a valid aliases file before replacing the
+	// current one with it.
+	restored, err := decodeAliases(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("decode backup: %w", err)
+	}
+
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return fmt.Errorf("create config dir: %w", err)
+	}
+	old, _ := loadAliases(aliasesPath())
+	if err := writeFileAtomic(aliasesPath(), data); err != nil {
+		return fmt.Errorf("restore aliases file: %w", err)
+	}
+	_ = recordModified(aliasesPath(), old, restored)
+	return nil
+}
+
+// copyToFile writes the contents of r to the file at path, replacing it.
+func copyToFile(path string, r io.Reader) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// binDirCache holds the result of goBinDir, which runs go env, so that it runs
+// at most once.
+var binDirCache struct {
+	done bool
+	dir  string
+	err  error
+}
+
+// goBinDir returns the directory go install writes binaries to, for commands
+// that need to find installed binaries, such as ago x and ago self-update.
+// Like go install, it uses GOBIN, then the bin directory of the first entry of
+// GOPATH, then that of the default GOPATH, ~/go.
+func goBinDir() (string, error) {
+	if !binDirCache.done {
+		binDirCache.dir, binDirCache.err = lookupGoBinDir()
+		binDirCache.done = true
+	}
+	return binDirCache.dir, binDirCache.err
+}
+
+// lookupGoBinDir carries out goBinDir, asking go env for GOBIN and GOPATH.
+func lookupGoBinDir() (string, error) {
+	out, err := exec.Command(goBin(), "env", "GOBIN", "GOPATH").Output()
+	if err != nil {
+		return "", fmt.Errorf("go env: %w", err)
+	}
+	if dir := binDirFromEnv(string(out)); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.New("could not determine the go install directory")
+	}
+	return filepath.Join(home, "go", "bin"), nil
+}
+
+// binDirFromEnv returns the install directory given out, the output of
+// go env GOBIN GOPATH, or "" if both are empty.
+func binDirFromEnv(out string) string {
+	// Only the final newline is trimmed, since an empty GOBIN is an empty
+	// first line.
+	lines := strings.Split(strings.TrimRight(out, "\r\n"), "\n")
+	if len(lines) > 0 && strings.TrimSpace(lines[0]) != "" {
+		return strings.TrimSpace(lines[0])
+	}
+	if len(lines) > 1 && strings.TrimSpace(lines[1]) != "" {
+		// GOPATH may be a list; go install uses the first entry.
+		gopath := filepath.SplitList(strings.TrimSpace(lines[1]))[0]
+		return filepath.Join(gopath, "bin")
+	}
+	return ""
+}
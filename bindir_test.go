@@ -0,0 +1,29 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBinDirFromEnv(t *testing.T) {
+	gopath := filepath.Join("home", "me", "go")
+	other := filepath.Join("srv", "go")
+	list := strings.Join([]string{gopath, other}, string(filepath.ListSeparator))
+	tests := []struct {
+		name, out, want string
+	}{
+		{"GOBIN set", "/opt/bin\n" + gopath + "\n", "/opt/bin"},
+		{"GOBIN empty", "\n" + gopath + "\n", filepath.Join(gopath, "bin")},
+		{"GOPATH list", "\n" + list + "\n", filepath.Join(gopath, "bin")},
+		{"both empty", "\n\n", ""},
+		{"no output", "", ""},
+		{"CRLF GOBIN", "C:\\bin\r\nC:\\go\r\n", "C:\\bin"},
+		{"CRLF GOBIN empty", "\r\n" + gopath + "\r\n", filepath.Join(gopath, "bin")},
+	}
+	for _, tt := range tests {
+		if got := binDirFromEnv(tt.out); got != tt.want {
+			t.Errorf("%s: binDirFromEnv(%q) = %q, want %q", tt.name, tt.out, got, tt.want)
+		}
+	}
+}
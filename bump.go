@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// splitMajor splits pkg into the module path without its major version
+// suffix and the major version, which is 1 if pkg has no suffix.
+func splitMajor(pkg string) (base string, major int) {
+	if idx := strings.LastIndex(pkg, "/v"); idx != -1 {
+		if n, err := strconv.Atoi(pkg[idx+2:]); err == nil && n >= 2 {
+			return pkg[:idx], n
+		}
+	}
+	return pkg, 1
+}
+
+// withMajor returns the path of major version major of the module at base,
+// which has no major version suffix.
+func withMajor(base string, major int) string {
+	if major < 2 {
+		return base
+	}
+	return base + "/v" + strconv.Itoa(major)
+}
+
+// checkPinMajor returns an error if version, to be pinned to the alias name
+// of pkg, is of another major version than the module path of pkg, as in
+// github.com/foo/bar/v2@v1.4.0, which go would never accept. Versions marked
+// +incompatible and gopkg.in paths, which have majors of their own, are
+// accepted.
+func checkPinMajor(name, pkg, version string) error {
+	if isPathAlias(pkg) || hasScheme(pkg) || strings.HasPrefix(pkg, "gopkg.in/") || semver.Build(version) == "+incompatible" {
+		return nil
+	}
+	_, major := splitMajor(strings.TrimSuffix(pkg, "/..."))
+	n, _ := strconv.Atoi(strings.TrimPrefix(semver.Major(version), "v"))
+	if n < 2 {
+		n = 1
+	}
+	if n != major {
+		return resolveErrorf(errInvalidVersion, "cannot pin alias %q to %s: %s is of major version v%d", name, version, pkg, major)
+	}
+	return nil
+}
+
+// parseMajor parses a major version of the form "v3".
+func parseMajor(s string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimPrefix(s, "v"))
+	if !strings.HasPrefix(s, "v") || err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid major version %q; want e.g. v3", s)
+	}
+	return n, nil
+}
+
+// maxMissingMajors is how many consecutive major versions latestMajor tries
+// without finding one before it gives up, since modules sometimes skip majors,
+// such as when adopting modules at v5.
+const maxMissingMajors = 5
+
+// latestMajor returns the highest major version of the module at base, which
+// has no major version suffix, querying the module proxy for each major
+// version after from in turn.
+func latestMajor(base string, from int) (int, error) {
+	latest := from
+	for n := from + 1; n <= latest+maxMissingMajors; n++ {
+		var stderr bytes.Buffer
+		cmd := goCommand("list", "-m", "-versions", withMajor(base, n))
+		cmd.Stdout = nil
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); isNetworkError(msg) {
+				return 0, fmt.Errorf("could not reach the module proxy for %s (are you offline?): %s", base, msg)
+			}
+			continue
+		}
+		// The module path is followed by its versions, if any.
+		if len(strings.Fields(string(out))) > 1 {
+			latest = n
+		}
+	}
+	return latest, nil
+}
+
+// bumpable returns an error if entry has no major version to bump, because it
+// is a bundle or its package isn't a module path.
+func bumpable(entry aliasEntry) error {
+	if entry.isBundle() {
+		return fmt.Errorf("%s is a bundle; bump each of its packages instead", entry.target())
+	}
+	if isPathAlias(entry.Package) || hasScheme(entry.Package) || strings.HasSuffix(entry.Package, "/...") {
+		return fmt.Errorf("%s is not a module path, so it has no major version", entry.Package)
+	}
+	return nil
+}
+
+// bumpedPackage returns the package of entry moved to major version to, or to
+// the highest published major version of its module if to is negative.
+func bumpedPackage(entry aliasEntry, to int) (string, error) {
+	base, major := splitMajor(entry.Package)
+	if to >= 0 {
+		return withMajor(base, to), nil
+	}
+	if err := requireNetwork("alias bump without --to"); err != nil {
+		return "", err
+	}
+	n, err := latestMajor(base, major)
+	if err != nil {
+		return "", err
+	}
+	return withMajor(base, n), nil
+}
+
+// normalizeMajor rewrites the major version suffix of the module path pkg the
+// way go expects it: a /v0 or /v1 suffix is dropped, since those majors have
+// no suffix, and the suffix of later majors is lowercased without leading
+// zeros, so that /V02 becomes /v2. A version after an "@" is kept. Packages
+// that aren't module paths are returned unchanged.
+func normalizeMajor(pkg string) string {
+	if isPathAlias(pkg) || hasScheme(pkg) || strings.HasPrefix(pkg, "gopkg.in/") {
+		return pkg
+	}
+	path, version, hasVersion := strings.Cut(pkg, "@")
+	idx := strings.LastIndex(path, "/")
+	if idx == -1 {
+		return pkg
+	}
+	elem := path[idx+1:]
+	if len(elem) < 2 || (elem[0] != 'v' && elem[0] != 'V') {
+		return pkg
+	}
+	n, err := strconv.Atoi(elem[1:])
+	if err != nil || strings.ContainsAny(elem[1:], "+-") {
+		return pkg
+	}
+	path = withMajor(path[:idx], n)
+	if hasVersion {
+		path += "@" + version
+	}
+	return path
+}
+
+// majorChange is a package rewritten by normalizeAliasMajors.
+type majorChange struct {
+	Alias string
+	From  string
+	To    string
+}
+
+// normalizeAliasMajors normalizes the major version suffix of the package, or
+// each bundled package, of every alias in place, and returns the changes made
+// sorted by alias.
+func normalizeAliasMajors(aliases map[string]aliasEntry) []majorChange {
+	var changes []majorChange
+	for _, name := range sortedNames(aliases) {
+		entry := aliases[name]
+		if entry.isBundle() {
+			bundle := make([]string, len(entry.Bundle))
+			for i, pkg := range entry.Bundle {
+				bundle[i] = normalizeMajor(pkg)
+				if bundle[i] != pkg {
+					changes = append(changes, majorChange{Alias: name, From: pkg, To: bundle[i]})
+				}
+			}
+			entry.Bundle = bundle
+		} else if pkg := normalizeMajor(entry.Package); pkg != entry.Package {
+			changes = append(changes, majorChange{Alias: name, From: entry.Package, To: pkg})
+			entry.Package = pkg
+		}
+		aliases[name] = entry
+	}
+	return changes
+}
+
+// nextVersion returns the release following the semantic version v: the
+// next minor version, or the next major version if major is set.
+func nextVersion(v string, major bool) (string, error) {
+	if !semver.IsValid(v) {
+		return "", fmt.Errorf("%s is not a semantic version", v)
+	}
+	majorMinor := strings.TrimPrefix(semver.MajorMinor(v), "v")
+	maj, min, _ := strings.Cut(majorMinor, ".")
+	x, _ := strconv.Atoi(maj)
+	y, _ := strconv.Atoi(min)
+	if major {
+		return fmt.Sprintf("v%d.0.0", x+1), nil
+	}
+	return fmt.Sprintf("v%d.%d.0", x, y+1), nil
+}
+
+// bumpResolution moves res, the resolution of an argument to ago get, from its
+// version, given in the argument or pinned by its alias, to the next minor or
+// major version. A major bump past v1 also moves the argument to the module
+// path of the new major, as if the major had been requested, and returns the
+// package the alias would have at it; otherwise the returned package is "".
+func bumpResolution(aliases map[string]aliasEntry, res *resolution, major bool) (string, error) {
+	if !res.Matched {
+		return "", fmt.Errorf("%q is not an alias, so it has no version to bump", res.Arg)
+	}
+	if res.Version == "" {
+		return "", fmt.Errorf("no version is known for alias %q; pin one with --version or give one with @", res.Alias)
+	}
+	next, err := nextVersion(res.Version, major)
+	if err != nil {
+		return "", fmt.Errorf("cannot bump alias %q: %w", res.Alias, err)
+	}
+	entry := aliases[res.Alias]
+	n, _ := strconv.Atoi(strings.TrimPrefix(semver.Major(next), "v"))
+	if !major || n < 2 || cfg.GopathMode || entry.RawMajor {
+		suffix := "@" + res.Version
+		for i, pkg := range res.Bundle {
+			res.Bundle[i] = strings.TrimSuffix(pkg, suffix) + "@" + next
+		}
+		res.Result = strings.TrimSuffix(res.Result, suffix) + "@" + next
+		if res.Bundle != nil {
+			res.Result = strings.Join(res.Bundle, " ")
+		}
+		res.Version = next
+		return "", nil
+	}
+
+	if err := bumpable(entry); err != nil {
+		return "", fmt.Errorf("cannot bump the major version of alias %q: %w", res.Alias, err)
+	}
+	pkg, err := bumpedPackage(entry, n)
+	if err != nil {
+		return "", err
+	}
+	// The subpath of the argument, if any, follows the alias and any major
+	// it requested.
+	arg, _, _ := strings.Cut(res.Arg, "@")
+	rest := strings.TrimPrefix(strings.TrimPrefix(arg, res.Alias), "/")
+	if res.Major != "" {
+		rest = strings.TrimPrefix(strings.TrimPrefix(rest, res.Major), "/")
+	}
+	if len(res.Chain) == 1 {
+		res.Result = joinPath(pkg, rest) + "@" + next
+		res.Major = semver.Major(next)
+		res.Version = next
+		return pkg, nil
+	}
+	// The alias refers to another alias, which resolves its package, so
+	// the argument is rewritten to request the new major and resolved
+	// again. An exact alias anywhere in the chain would not match it.
+	for _, name := range res.Chain {
+		if aliases[name].ExactMatch || cfg.ExactMatch {
+			return "", fmt.Errorf("cannot bump the major version of alias %q: exact alias %q in its chain would not match the new major", res.Alias, name)
+		}
+	}
+	bumped, err := resolve(aliases, joinPath(joinPath(res.Alias, semver.Major(next)), rest)+"@"+next)
+	if err != nil {
+		return "", err
+	}
+	bumped.Arg = res.Arg
+	*res = bumped
+	return pkg, nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckPinMajor(t *testing.T) {
+	tests := []struct {
+		pkg, version string
+		ok           bool
+	}{
+		{"github.com/p/q", "v1.4.0", true},
+		{"github.com/p/q", "v0.3.0", true},
+		{"github.com/p/q", "v2.0.0", false},
+		{"github.com/p/q", "v2.0.0+incompatible", true},
+		{"github.com/p/q/v2", "v2.1.0", true},
+		{"github.com/p/q/v2", "v1.4.0", false},
+		{"github.com/p/q/v2", "v3.0.0", false},
+		{"github.com/p/q/v2/...", "v2.1.0", true},
+		{"gopkg.in/yaml.v3", "v3.0.1", true},
+		{"./tools", "v2.0.0", true},
+	}
+	for _, tt := range tests {
+		err := checkPinMajor("foo", tt.pkg, tt.version)
+		if tt.ok && err != nil {
+			t.Errorf("checkPinMajor(%q, %q) failed: %v", tt.pkg, tt.version, err)
+		}
+		if !tt.ok && !errors.Is(err, errInvalidVersion) {
+			t.Errorf("checkPinMajor(%q, %q) = %v, want an invalid version error", tt.pkg, tt.version, err)
+		}
+	}
+}
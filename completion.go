@@ -0,0 +1,29 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// writeCompletionData writes the names of aliases to w, sorted, for shell
+// completion scripts to read. With desc, each name is followed by a tab and
+// its description, if it has one, with any tabs and newlines in the
+// description replaced by spaces. Names end with a newline, or with a NUL
+// byte if null is set.
+func writeCompletionData(w io.Writer, aliases map[string]aliasEntry, desc, null bool) error {
+	end := byte('\n')
+	if null {
+		end = 0
+	}
+	bw := bufio.NewWriter(w)
+	for _, name := range sortedNames(aliases) {
+		bw.WriteString(name)
+		if d := aliases[name].Description; desc && d != "" {
+			bw.WriteByte('\t')
+			bw.WriteString(strings.Join(strings.Fields(d), " "))
+		}
+		bw.WriteByte(end)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const configFile = "config.json"
+
+// settings holds ago's configuration, read from config.json in the config dir.
+// Environment variables override the file.
+type settings struct {
+	// GopathMode disables the major version handling of module paths, for
+	// users of GOPATH mode. It is also enabled by AGO_GOPATH_MODE, or when
+	// GO111MODULE is off.
+	GopathMode bool `json:"gopathMode,omitempty"`
+	// ModuleRoot is the directory relative directory aliases are resolved
+	// against. If empty, they are relative to the working directory. It is
+	// overridden by AGO_MODULE_ROOT and the --module-root flag.
+	ModuleRoot string `json:"moduleRoot,omitempty"`
+	// ExtraArgs are added after the command name when ago runs one of the
+	// commands it expands aliases for. It is overridden by AGO_EXTRA_ARGS,
+	// which is split into words like a shell would.
+	ExtraArgs []string `json:"extraArgs,omitempty"`
+	// ExactMatch makes every alias match only arguments that are exactly
+	// the alias name, optionally with a version. It is also enabled by
+	// AGO_EXACT_MATCH.
+	ExactMatch bool `json:"exactMatch,omitempty"`
+	// RecordInstalls records every package installed by ago install in the
+	// installed manifest, as if --record were given. It is also enabled by
+	// AGO_RECORD_INSTALLS.
+	RecordInstalls bool `json:"recordInstalls,omitempty"`
+	// GoBin is the go command ago runs. If empty, go is looked up in PATH.
+	// It is overridden by AGO_GO_BIN.
+	GoBin string `json:"goBin,omitempty"`
+	// ConfirmDestructive controls when destructive commands, such as
+	// removing aliases, and fetches of many packages ask for
+	// confirmation: always, never, or tty, the default, to ask only when
+	// standard input is a terminal. It is overridden by
+	// AGO_CONFIRM_DESTRUCTIVE.
+	ConfirmDestructive string `json:"confirmDestructive,omitempty"`
+	// Retries is how many times ago get and ago install run go again when
+	// it fails with what looks like a transient network error. It is
+	// overridden by AGO_RETRIES.
+	Retries int `json:"retries,omitempty"`
+	// Format is the format of the aliases file. Only json is supported;
+	// reading a file that is evidently in another format fails with an
+	// error saying so. It is overridden by AGO_CONFIG_FORMAT.
+	Format string `json:"format,omitempty"`
+}
+
+// Values of the confirmDestructive setting.
+const (
+	confirmAlways = "always"
+	confirmNever  = "never"
+	confirmTTY    = "tty"
+)
+
+// cfg is the active configuration, loaded by loadSettings.
+var cfg settings
+
+// moduleRootFlag is the value of the --module-root flag.
+var moduleRootFlag string
+
+// loadSettings reads the config file, if there is one, and applies any
+// overrides from the environment and then from project, the project config.
+func loadSettings(project projectConfig) (settings, error) {
+	var s settings
+	data, err := os.ReadFile(filepath.Join(configDir, configFile))
+	if err != nil && !os.IsNotExist(err) {
+		return s, fmt.Errorf("read config file: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return s, fmt.Errorf("decode config file %s: %w", filepath.Join(configDir, configFile), err)
+		}
+	}
+
+	if root := os.Getenv("AGO_MODULE_ROOT"); root != "" {
+		s.ModuleRoot = root
+	}
+	if moduleRootFlag != "" {
+		s.ModuleRoot = moduleRootFlag
+	}
+	if s.ModuleRoot != "" {
+		root, err := filepath.Abs(s.ModuleRoot)
+		if err != nil {
+			return s, fmt.Errorf("resolve module root: %w", err)
+		}
+		s.ModuleRoot = root
+	}
+
+	if extra := os.Getenv("AGO_EXTRA_ARGS"); extra != "" {
+		words, err := splitShellWords(extra)
+		if err != nil {
+			return s, fmt.Errorf("parse AGO_EXTRA_ARGS: %w", err)
+		}
+		s.ExtraArgs = words
+	}
+
+	if envBool("AGO_EXACT_MATCH") {
+		s.ExactMatch = true
+	}
+	if envBool("AGO_RECORD_INSTALLS") {
+		s.RecordInstalls = true
+	}
+	if envBool("AGO_GOPATH_MODE") || os.Getenv("GO111MODULE") == "off" {
+		s.GopathMode = true
+	}
+	if goBin := os.Getenv("AGO_GO_BIN"); goBin != "" {
+		s.GoBin = goBin
+	}
+	if confirm := os.Getenv("AGO_CONFIRM_DESTRUCTIVE"); confirm != "" {
+		s.ConfirmDestructive = confirm
+	}
+	if retries := os.Getenv("AGO_RETRIES"); retries != "" {
+		n, err := strconv.Atoi(retries)
+		if err != nil {
+			return s, fmt.Errorf("parse AGO_RETRIES: %q is not a number", retries)
+		}
+		s.Retries = n
+	}
+	if s.Retries < 0 {
+		return s, fmt.Errorf("invalid retries %d; want 0 or more", s.Retries)
+	}
+	if format := os.Getenv("AGO_CONFIG_FORMAT"); format != "" {
+		s.Format = format
+	}
+	if s.Format == "" {
+		s.Format = formatJSON
+	}
+	if !aliasFormats[s.Format] {
+		return s, fmt.Errorf("unsupported format %q; the aliases file can only be json", s.Format)
+	}
+	switch s.ConfirmDestructive {
+	case "":
+		s.ConfirmDestructive = confirmTTY
+	case confirmAlways, confirmNever, confirmTTY:
+	default:
+		return s, fmt.Errorf("invalid confirmDestructive %q; want always, never or tty", s.ConfirmDestructive)
+	}
+
+	if project.GoBin != "" {
+		s.GoBin = project.GoBin
+	}
+	if len(project.ExtraArgs) > 0 {
+		s.ExtraArgs = project.ExtraArgs
+	}
+	return s, nil
+}
+
+// goBin returns the go command ago runs.
+func goBin() string {
+	if cfg.GoBin != "" {
+		return cfg.GoBin
+	}
+	return "go"
+}
+
+// envBool reports whether the environment variable name is set to a value
+// other than "", "0" or "false".
+func envBool(name string) bool {
+	switch os.Getenv(name) {
+	case "", "0", "false":
+		return false
+	}
+	return true
+}
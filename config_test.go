@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestLoadSettingsGopathMode(t *testing.T) {
+	saved := configDir
+	t.Cleanup(func() { configDir = saved })
+	configDir = t.TempDir()
+
+	tests := []struct {
+		gopathMode, go111module string
+		want                    bool
+	}{
+		{"", "", false},
+		{"", "on", false},
+		{"", "off", true},
+		{"1", "", true},
+		{"true", "on", true},
+	}
+	for _, tt := range tests {
+		t.Setenv("AGO_GOPATH_MODE", tt.gopathMode)
+		t.Setenv("GO111MODULE", tt.go111module)
+		s, err := loadSettings(projectConfig{})
+		if err != nil {
+			t.Errorf("AGO_GOPATH_MODE=%q GO111MODULE=%q: loadSettings failed: %v", tt.gopathMode, tt.go111module, err)
+			continue
+		}
+		if s.GopathMode != tt.want {
+			t.Errorf("AGO_GOPATH_MODE=%q GO111MODULE=%q: GopathMode = %v, want %v", tt.gopathMode, tt.go111module, s.GopathMode, tt.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Ways of choosing which alias of a group sharing a target to keep.
+const (
+	keepAll      = "all"
+	keepShortest = "shortest"
+	keepFirst    = "first"
+)
+
+// duplicateTargets returns the groups of aliases that expand to the same
+// target, each sorted by name, in the order of their first names.
+func duplicateTargets(aliases map[string]aliasEntry) [][]string {
+	byTarget := make(map[string][]string)
+	for _, name := range sortedNames(aliases) {
+		target := aliases[name].target()
+		byTarget[target] = append(byTarget[target], name)
+	}
+	var groups [][]string
+	for _, names := range byTarget {
+		if len(names) > 1 {
+			groups = append(groups, names)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i][0] < groups[j][0]
+	})
+	return groups
+}
+
+// keptAlias returns the alias of group to keep. With keepShortest, that is the
+// alias with the shortest name. With keepFirst, it is the first alias, by
+// name, of those in existing, the aliases defined before an import, or of the
+// whole group if none were.
+func keptAlias(group []string, keep string, existing map[string]aliasEntry) string {
+	switch keep {
+	case keepShortest:
+		kept := group[0]
+		for _, name := range group[1:] {
+			if len(name) < len(kept) {
+				kept = name
+			}
+		}
+		return kept
+	case keepFirst:
+		for _, name := range group {
+			if _, ok := existing[name]; ok {
+				return name
+			}
+		}
+		return group[0]
+	}
+	return ""
+}
+
+// dedupeAliases reports each group of aliases sharing a target and, unless
+// keep is keepAll, removes all but the kept alias of each group from aliases.
+// It returns the aliases removed.
+func dedupeAliases(aliases map[string]aliasEntry, keep string, existing map[string]aliasEntry) map[string]aliasEntry {
+	removed := make(map[string]aliasEntry)
+	for _, group := range duplicateTargets(aliases) {
+		target := aliases[group[0]].target()
+		kept := keptAlias(group, keep, existing)
+		if kept == "" {
+			fmt.Printf("%s: %s\n", target, strings.Join(group, ", "))
+			continue
+		}
+		for _, name := range group {
+			if name != kept {
+				removed[name] = aliases[name]
+				delete(aliases, name)
+			}
+		}
+		fmt.Printf("%s: %s (kept %s)\n", target, strings.Join(group, ", "), kept)
+	}
+	return removed
+}
+
+// validKeep reports whether keep is a valid value of the --keep flag.
+func validKeep(keep string) bool {
+	return keep == keepAll || keep == keepShortest || keep == keepFirst
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/mod/modfile"
+)
+
+// printAliasDeps prints the modules required by the go.mod file at path,
+// along with the aliases that refer to them. Modules without an alias are
+// given a suggested alias name. If path is empty, the go.mod file of the
+// current module is used.
+func printAliasDeps(aliases map[string]aliasEntry, path string) error {
+	if path == "" {
+		out, err := exec.Command(goBin(), "env", "GOMOD").Output()
+		if err != nil {
+			return fmt.Errorf("go env GOMOD: %w", err)
+		}
+		path = strings.TrimSpace(string(out))
+		if path == "" || path == os.DevNull {
+			return errors.New("no go.mod found; run inside a module or give the path to a go.mod file")
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read go.mod: %w", err)
+	}
+	file, err := modfile.Parse(path, data, nil)
+	if err != nil {
+		return fmt.Errorf("parse go.mod: %w", err)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MODULE\tALIASES\tSUGGESTED")
+	fmt.Fprintln(tw, "------\t-------\t---------")
+	for _, req := range file.Require {
+		mod := req.Mod.Path
+		var names []string
+		for name, entry := range aliases {
+			for _, pkg := range append([]string{entry.Package}, entry.Bundle...) {
+				if pkg == mod || strings.HasPrefix(pkg, mod+"/") {
+					names = append(names, name)
+					break
+				}
+			}
+		}
+		sort.Strings(names)
+
+		aliased, suggested := strings.Join(names, ", "), "-"
+		if len(names) == 0 {
+			aliased = "-"
+			suggested = suggestAliasName(aliases, mod)
+		}
+		if req.Indirect {
+			mod += " (indirect)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", mod, aliased, suggested)
+	}
+	return tw.Flush()
+}
+
+// suggestAliasName suggests a name for an alias to the module at modPath: the
+// last element of the path, ignoring any major version suffix. If that name
+// is taken, "-" is returned.
+func suggestAliasName(aliases map[string]aliasEntry, modPath string) string {
+	name := binaryName(modPath)
+	if _, taken := aliases[name]; taken || name == "" {
+		return "-"
+	}
+	return name
+}
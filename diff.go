@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"golang.org/x/term"
+)
+
+// aliasChange is an alias whose package differs between two sets of aliases.
+type aliasChange struct {
+	Alias string `json:"alias"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// aliasDiff is the difference from one set of aliases to another.
+type aliasDiff struct {
+	Added   map[string]aliasEntry `json:"added"`
+	Removed map[string]aliasEntry `json:"removed"`
+	Changed []aliasChange         `json:"changed"`
+}
+
+// diffAliases returns the difference from the aliases in from to those in to.
+// Aliases present in both are changed if their packages or bundles differ.
+func diffAliases(from, to map[string]aliasEntry) aliasDiff {
+	diff := aliasDiff{
+		Added:   make(map[string]aliasEntry),
+		Removed: make(map[string]aliasEntry),
+		Changed: []aliasChange{},
+	}
+	for name, entry := range to {
+		prev, ok := from[name]
+		switch {
+		case !ok:
+			diff.Added[name] = entry
+		case prev.target() != entry.target():
+			diff.Changed = append(diff.Changed, aliasChange{Alias: name, From: prev.target(), To: entry.target()})
+		}
+	}
+	for name, entry := range from {
+		if _, ok := to[name]; !ok {
+			diff.Removed[name] = entry
+		}
+	}
+	sort.Slice(diff.Changed, func(i, j int) bool {
+		return diff.Changed[i].Alias < diff.Changed[j].Alias
+	})
+	return diff
+}
+
+// ANSI escape sequences used to color diff output.
+const (
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorReset  = "\x1b[0m"
+)
+
+// printAliasDiff prints diff to standard output, or as JSON if asJSON is set.
+// Lines are colored when standard output is a terminal, unless the NO_COLOR
+// environment variable is set.
+func printAliasDiff(diff aliasDiff, asJSON bool) error {
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(diff)
+	}
+	if len(diff.Added)+len(diff.Removed)+len(diff.Changed) == 0 {
+		fmt.Println("no differences")
+		return nil
+	}
+
+	color := term.IsTerminal(int(os.Stdout.Fd())) && os.Getenv("NO_COLOR") == ""
+	paint := func(c, s string) string {
+		if !color {
+			return s
+		}
+		return c + s + colorReset
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, name := range sortedNames(diff.Added) {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", paint(colorGreen, "+"), name, diff.Added[name].target())
+	}
+	for _, name := range sortedNames(diff.Removed) {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", paint(colorRed, "-"), name, diff.Removed[name].target())
+	}
+	for _, c := range diff.Changed {
+		fmt.Fprintf(tw, "%s\t%s\t%s -> %s\n", paint(colorYellow, "~"), c.Alias, c.From, c.To)
+	}
+	return tw.Flush()
+}
+
+// sortedNames returns the names of aliases in sorted order.
+func sortedNames(aliases map[string]aliasEntry) []string {
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// envVar is a setting printed by ago env, named after the environment
+// variable that sets it.
+type envVar struct {
+	Name, Value string
+}
+
+// agoEnv returns ago's effective settings, in the order ago env prints them.
+// layers holds the aliases in effect, to count those of each source.
+func agoEnv(layers aliasLayers) []envVar {
+	projectPath, _ := findProjectFile()
+	profileName := profile
+	if profileName == "" {
+		profileName = defaultProfile
+	}
+	return []envVar{
+		{"AGO_CONFIG_DIR", configDir},
+		{"AGO_CONFIG_FORMAT", cfg.Format},
+		{"AGO_PROFILE", profileName},
+		{"AGO_ALIASES_FILE", aliasesPath()},
+		{"AGO_PROJECT_FILE", projectPath},
+		{"AGO_PROJECT_MAX_DEPTH", os.Getenv("AGO_PROJECT_MAX_DEPTH")},
+		{"AGO_PROJECT_STOP_AT_GIT", strconv.FormatBool(envBool("AGO_PROJECT_STOP_AT_GIT"))},
+		{"AGO_GO_BIN", goBin()},
+		{"AGO_MODULE_ROOT", cfg.ModuleRoot},
+		{"AGO_EXTRA_ARGS", strings.Join(cfg.ExtraArgs, " ")},
+		{"AGO_EXACT_MATCH", strconv.FormatBool(cfg.ExactMatch)},
+		{"AGO_GOPATH_MODE", strconv.FormatBool(cfg.GopathMode)},
+		{"AGO_RECORD_INSTALLS", strconv.FormatBool(cfg.RecordInstalls)},
+		{"AGO_CONFIRM_DESTRUCTIVE", cfg.ConfirmDestructive},
+		{"AGO_RETRIES", strconv.Itoa(cfg.Retries)},
+		{"AGO_OFFLINE", strconv.FormatBool(offline)},
+		{"AGO_NO_EXPAND", strconv.FormatBool(noExpand)},
+		{"AGO_TRACE", strconv.FormatBool(traceEnabled)},
+		{"AGO_ALIAS_COUNT", strconv.Itoa(len(layers.merged()))},
+		{"AGO_GLOBAL_ALIAS_COUNT", strconv.Itoa(len(layers.Global))},
+		{"AGO_ENV_ALIAS_COUNT", strconv.Itoa(len(layers.Env))},
+		{"AGO_PROJECT_ALIAS_COUNT", strconv.Itoa(len(layers.Project))},
+	}
+}
+
+// isAgoEnvKey reports whether key names one of ago's settings, rather than
+// one of go's, which ago env leaves to go env.
+func isAgoEnvKey(key string) bool {
+	return strings.HasPrefix(key, "AGO_")
+}
+
+// printAgoEnv writes the settings vars to w in the form of go env: the
+// values of keys alone, one per line, or every setting as NAME='value'. With
+// asJSON, they are written as a JSON object instead.
+func printAgoEnv(w io.Writer, vars []envVar, keys []string, asJSON bool) error {
+	byName := make(map[string]string, len(vars))
+	for _, v := range vars {
+		byName[v.Name] = v.Value
+	}
+	for _, key := range keys {
+		if _, ok := byName[key]; !ok {
+			return fmt.Errorf("unknown setting %s", key)
+		}
+	}
+	if asJSON {
+		out := byName
+		if len(keys) > 0 {
+			out = make(map[string]string, len(keys))
+			for _, key := range keys {
+				out[key] = byName[key]
+			}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(out)
+	}
+	if len(keys) > 0 {
+		for _, key := range keys {
+			fmt.Fprintln(w, byName[key])
+		}
+		return nil
+	}
+	for _, v := range vars {
+		fmt.Fprintf(w, "%s='%s'\n", v.Name, strings.ReplaceAll(v.Value, "'", `'\''`))
+	}
+	return nil
+}
+
+// runEnv carries out ago env with args, the arguments following the command.
+// Keys of go's settings, and flags such as -w, are passed to go env instead.
+// It reports whether it handled the command.
+func runEnv(layers aliasLayers, args []string) (bool, error) {
+	var asJSON bool
+	var keys []string
+	for _, arg := range args {
+		switch {
+		case arg == "--json" || arg == "-json":
+			asJSON = true
+		case isAgoEnvKey(arg):
+			keys = append(keys, arg)
+		default:
+			return false, nil
+		}
+	}
+	return true, printAgoEnv(os.Stdout, agoEnv(layers), keys, asJSON)
+}
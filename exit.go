@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Exit statuses for errors in ago itself, taken from the BSD sysexits.h
+// range, so that they can be told apart from the status of the go command,
+// which is passed through verbatim.
+const (
+	exitUsage       = 64 // the command was used incorrectly
+	exitDataErr     = 65 // an alias, version or argument is invalid
+	exitNoInput     = 66 // an alias, backup or file does not exist
+	exitUnavailable = 69 // the go command or the network is unavailable
+	exitSoftware    = 70 // any other error
+	exitIOErr       = 74 // a file could not be written
+	exitNoPerm      = 77 // the user declined to confirm
+	exitConfig      = 78 // the config or aliases file could not be loaded
+)
+
+// exitWithError exits with the status of err if it is an *exec.ExitError from
+// a command ago ran, so that the command's status is passed through.
+// Otherwise, err is printed and ago exits with one of its own statuses.
+func exitWithError(err error) {
+	var exitErr *exec.ExitError
+	if ok := errors.As(err, &exitErr); ok {
+		os.Exit(exitErr.ExitCode())
+	}
+	if errors.Is(err, exec.ErrNotFound) {
+		fatalf(exitUnavailable, "error: %v", err)
+	}
+	fatalf(exitSoftware, "error: %v", err)
+}
+
+// fatalf prints a message to standard error and exits with status code.
+func fatalf(code int, format string, args ...interface{}) {
+	if !strings.HasSuffix(format, "\n") {
+		format += "\n"
+	}
+	fmt.Fprintf(os.Stderr, format, args...)
+	os.Exit(code)
+}
@@ -0,0 +1,572 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// The kinds of error resolving an argument fails with, which can be told
+// apart with errors.Is.
+var (
+	// errAliasCycle is an alias that refers back to itself, directly or
+	// through other aliases.
+	errAliasCycle = errors.New("alias cycle")
+	// errMisusedAlias is an alias used in a way its kind doesn't allow,
+	// such as a bundle with a subpath, or a directory with a version.
+	errMisusedAlias = errors.New("misused alias")
+	// errInvalidVersion is an argument with an invalid version.
+	errInvalidVersion = errors.New("invalid version")
+)
+
+// resolveError is an error resolving an argument. Its message stands on its
+// own; Kind is one of the kinds above, which errors.Is reports it as.
+type resolveError struct {
+	Kind error
+	Msg  string
+}
+
+func (e *resolveError) Error() string { return e.Msg }
+func (e *resolveError) Unwrap() error { return e.Kind }
+
+// resolveErrorf returns a resolveError of the given kind, with a message
+// formatted as by fmt.Sprintf.
+func resolveErrorf(kind error, format string, args ...interface{}) error {
+	return &resolveError{Kind: kind, Msg: fmt.Sprintf(format, args...)}
+}
+
+// resolveExitCode returns the exit status for err, an error resolving an
+// argument: a data error for the kinds of resolveError, which are mistakes in
+// the argument or the aliases, and a software error for any other, such as
+// failing to find the home directory.
+func resolveExitCode(err error) int {
+	if errors.Is(err, errAliasCycle) || errors.Is(err, errMisusedAlias) || errors.Is(err, errInvalidVersion) {
+		return exitDataErr
+	}
+	return exitSoftware
+}
+
+// expandArg rewrites arg using the alias with the longest matching prefix. If
+// no alias matches, arg is returned unchanged.
+func expandArg(aliases map[string]aliasEntry, arg string) (string, error) {
+	res, err := resolvePackage(aliases, arg)
+	if err != nil {
+		return "", err
+	}
+	return res.Result, nil
+}
+
+// resolvePackage resolves arg like expandArg, for callers that also need the
+// aliases it was resolved with, such as to count their use.
+func resolvePackage(aliases map[string]aliasEntry, arg string) (resolution, error) {
+	res, err := resolve(aliases, arg)
+	if err == nil && res.Bundle != nil {
+		err = bundleError(res)
+	}
+	return res, err
+}
+
+// resolveDoc resolves arg, the package argument of go doc. The argument may
+// name a symbol in the package as pkg.Sym, so an alias followed directly by a
+// dot and a symbol is expanded on its own and the symbol kept as it is, rather
+// than joined to the package as a subpath.
+func resolveDoc(aliases map[string]aliasEntry, arg string) (resolution, error) {
+	if alias, _ := matchAlias(aliases, arg, false); alias != "" && !strings.HasSuffix(alias, ".") {
+		if sym := arg[len(alias):]; strings.HasPrefix(sym, ".") && len(sym) > 1 && !strings.ContainsAny(sym, "/@") {
+			res, err := resolvePackage(aliases, alias)
+			res.Arg = arg
+			res.Result += sym
+			return res, err
+		}
+	}
+	return resolvePackage(aliases, arg)
+}
+
+// bundleError returns the error for res, which resolved to a bundle, being
+// used where one package is expected.
+func bundleError(res resolution) error {
+	return resolveErrorf(errMisusedAlias, "alias %q is a bundle of several packages and cannot be used where one package is expected", res.Chain[len(res.Chain)-1])
+}
+
+// printExpanded writes the expansion of each of args to w, one per line and
+// nothing else, as ago which and ago expand promise, so that their output can
+// be used by scripts and go:generate directives as is. It stops at the first
+// argument that can't be expanded, and returns the aliases used by those that
+// were.
+func printExpanded(w io.Writer, aliases map[string]aliasEntry, args []string) (used []string, err error) {
+	for _, arg := range args {
+		res, err := resolvePackage(aliases, arg)
+		if err != nil {
+			return used, err
+		}
+		used = append(used, res.Chain...)
+		if _, err := fmt.Fprintln(w, res.Result); err != nil {
+			return used, err
+		}
+	}
+	return used, nil
+}
+
+// pathAliases returns the aliases of directories, leaving out those of
+// packages, for commands such as go work use that only take directories.
+func pathAliases(aliases map[string]aliasEntry) map[string]aliasEntry {
+	dirs := make(map[string]aliasEntry)
+	for name, entry := range aliases {
+		if !entry.isBundle() && isPathAlias(entry.Package) {
+			dirs[name] = entry
+		}
+	}
+	return dirs
+}
+
+// resolution describes how an argument was rewritten by resolve.
+type resolution struct {
+	// Arg is the argument as given by the user.
+	Arg string `json:"arg"`
+	// Matched reports whether an alias matched Arg.
+	Matched bool `json:"matched"`
+	// Alias is the name of the matched alias.
+	Alias string `json:"alias,omitempty"`
+	// Major is the major version requested in Arg, e.g. "v2".
+	Major string `json:"major,omitempty"`
+	// Version is the version requested in Arg, without the leading "@".
+	Version string `json:"version,omitempty"`
+	// Chain lists the aliases that were expanded, in order. It has more
+	// than one entry when an alias refers to another alias.
+	Chain []string `json:"chain,omitempty"`
+	// Result is the rewritten argument. For bundles, it holds the packages
+	// of Bundle separated by spaces.
+	Result string `json:"result"`
+	// Bundle lists the packages Arg was rewritten to, if it named a bundle.
+	Bundle []string `json:"bundle,omitempty"`
+	// Error describes why Arg could not be rewritten, if it couldn't.
+	Error string `json:"error,omitempty"`
+}
+
+// resolve rewrites arg using the alias with the longest matching prefix,
+// recording each decision in the returned resolution. An alias may refer to
+// another alias, in which case the result is resolved again until no alias
+// matches. Resolving an alias a second time is reported as a cycle.
+func resolve(aliases map[string]aliasEntry, arg string) (resolution, error) {
+	res := resolution{Arg: arg, Result: arg}
+	tracef("resolve %q", arg)
+	if isPseudoPackage(arg) {
+		tracef("%q is a pseudo-package, left alone", arg)
+		return res, nil
+	}
+	for {
+		// Only the argument given by the user is matched by prefix. An
+		// alias referring to another alias must name it exactly, so
+		// that packages which merely begin with an alias name aren't
+		// expanded again.
+		alias, entry := matchAlias(aliases, res.Result, len(res.Chain) > 0)
+		if alias == "" {
+			tracef("no alias matches %q, done", res.Result)
+			return res, nil
+		}
+		tracef("alias %q matches %q (chain depth %d)", alias, res.Result, len(res.Chain))
+		for _, a := range res.Chain {
+			if a == alias {
+				return res, resolveErrorf(errAliasCycle, "alias cycle detected: %s -> %s", strings.Join(res.Chain, " -> "), alias)
+			}
+		}
+		res.Chain = append(res.Chain, alias)
+
+		if entry.isBundle() {
+			bundle, version, err := expandBundle(res.Result, alias, entry)
+			if err != nil {
+				return res, err
+			}
+			if !res.Matched {
+				res.Matched = true
+				res.Alias = alias
+				res.Version = version
+			}
+			res.Bundle = bundle
+			res.Result = strings.Join(bundle, " ")
+			tracef("bundle %q expands to %q", alias, bundle)
+			return res, nil
+		}
+
+		exp, err := expandAlias(res.Result, alias, entry)
+		if err != nil {
+			return res, err
+		}
+		if !res.Matched {
+			res.Matched = true
+			res.Alias = alias
+			res.Major = exp.major
+			res.Version = exp.version
+		}
+		tracef("%q -> %q", res.Result, exp.result)
+		res.Result = exp.result
+	}
+}
+
+// valueFlags are the flags of the go commands ago expands that may take their
+// value as the next argument, such as the output path of go build -o. Values
+// are never expanded.
+var valueFlags = map[string]bool{
+	"C": true, "o": true, "p": true, "asmflags": true, "buildmode": true,
+	"compiler": true, "gccgoflags": true, "gcflags": true, "installsuffix": true,
+	"ldflags": true, "mod": true, "modfile": true, "overlay": true, "pgo": true,
+	"pkgdir": true, "tags": true, "toolexec": true, "exec": true,
+	"bench": true, "benchtime": true, "blockprofile": true, "count": true,
+	"coverpkg": true, "covermode": true, "coverprofile": true, "cpu": true,
+	"cpuprofile": true, "fuzz": true, "fuzztime": true, "fuzzminimizetime": true,
+	"list": true, "memprofile": true, "mutexprofile": true, "outputdir": true,
+	"parallel": true, "run": true, "shuffle": true, "skip": true,
+	"timeout": true, "trace": true, "vet": true, "vettool": true,
+	"blockprofilerate": true, "memprofilerate": true, "mutexprofilefraction": true,
+}
+
+// takesValue reports whether arg is a flag whose value is the next argument.
+func takesValue(arg string) bool {
+	if !strings.HasPrefix(arg, "-") || strings.Contains(arg, "=") {
+		return false
+	}
+	return valueFlags[strings.TrimLeft(arg, "-")]
+}
+
+// endsPackages reports whether arg, an argument of the go command cmd, ends
+// the arguments that may name packages. Whatever follows a -- separator, or
+// the -args flag of go test, is meant for the program or test binary, and is
+// never expanded.
+func endsPackages(cmd, arg string) bool {
+	return arg == "--" || (cmd == "test" && (arg == "-args" || arg == "--args"))
+}
+
+// expandArgs returns args, the arguments following the go command cmd, with
+// their aliases expanded. Flags, such as -insecure, are passed through, and so
+// are flag values, such as the output path of -o, and everything after an
+// argument that ends the packages. The get and install commands apply pinned
+// versions, and a bundle expands to one argument per package. If each is not
+// nil, it is called with the resolution of every package argument before it is
+// substituted, and may change it.
+func expandArgs(aliases map[string]aliasEntry, cmd string, args []string, each func(res *resolution)) ([]string, error) {
+	expanded := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if endsPackages(cmd, arg) {
+			tracef("%q ends the packages; the rest is passed through", arg)
+			return append(expanded, args[i:]...), nil
+		}
+		if strings.HasPrefix(arg, "-") {
+			tracef("flag %q passed through", arg)
+			expanded = append(expanded, arg)
+			if takesValue(arg) && i+1 < len(args) {
+				tracef("value %q of flag %q passed through", args[i+1], arg)
+				i++
+				expanded = append(expanded, args[i])
+			}
+			continue
+		}
+		res, err := resolve(aliases, arg)
+		if err != nil {
+			return nil, err
+		}
+		if cmd == "get" || cmd == "install" {
+			applyPinnedVersion(aliases, &res)
+		}
+		if each != nil {
+			each(&res)
+		}
+		if res.Bundle != nil {
+			expanded = append(expanded, res.Bundle...)
+			continue
+		}
+		expanded = append(expanded, res.Result)
+	}
+	return expanded, nil
+}
+
+// isPseudoPackage reports whether arg names one of the go command's pseudo
+// packages, which are never expanded by an alias: the go and toolchain
+// pseudo-modules, as in go get go@1.22, or the meta-packages tool, all, std,
+// cmd and main, as in go install tool or go list -m all.
+func isPseudoPackage(arg string) bool {
+	switch arg {
+	case "tool", "all", "std", "cmd", "main":
+		return true
+	}
+	name, _, _ := strings.Cut(arg, "@")
+	return name == "go" || name == "toolchain"
+}
+
+// matchAlias returns the alias with the longest prefix of arg, and its
+// definition. If boundary is set, the alias must be followed in arg by a
+// slash, an @ or nothing at all. Aliases in exact match mode must be followed
+// by an @ or nothing at all. Dots and slashes within alias names have no
+// special meaning: the alias k8s.io matches k8s.io/client-go like any other
+// prefix. If no alias matches, matchAlias returns an empty alias name.
+func matchAlias(aliases map[string]aliasEntry, arg string, boundary bool) (alias string, entry aliasEntry) {
+	for a, e := range aliases {
+		// An empty alias would match every argument, so ignore any that
+		// made it into the aliases file.
+		if strings.TrimSpace(a) == "" {
+			continue
+		}
+		if !strings.HasPrefix(arg, a) || len(a) <= len(alias) {
+			continue
+		}
+		if boundary && len(arg) > len(a) && !strings.HasSuffix(a, "/") {
+			if c := arg[len(a)]; c != '/' && c != '@' {
+				continue
+			}
+		}
+		// Exact aliases only match the alias name itself, optionally
+		// followed by a version.
+		if (e.ExactMatch || cfg.ExactMatch) && len(arg) > len(a) && arg[len(a)] != '@' {
+			continue
+		}
+		alias = a
+		entry = e
+	}
+	return alias, entry
+}
+
+// expansion is the result of expanding a single alias.
+type expansion struct {
+	result  string
+	major   string
+	version string
+}
+
+// applyPinnedVersion adds the version pinned by the aliases res was resolved
+// with to res, if the argument didn't give a version. Only the get and install
+// commands accept versions, so only they apply pinned versions.
+func applyPinnedVersion(aliases map[string]aliasEntry, res *resolution) {
+	if !res.Matched || res.Version != "" {
+		return
+	}
+	var version string
+	for _, name := range res.Chain {
+		entry := aliases[name]
+		if isPathAlias(entry.Package) || hasScheme(entry.Package) {
+			return
+		}
+		if version == "" {
+			version = entry.Version
+		}
+	}
+	if version == "" {
+		return
+	}
+	tracef("applying version %s pinned by the chain %v", version, res.Chain)
+	res.Version = version
+	if res.Bundle != nil {
+		for i := range res.Bundle {
+			res.Bundle[i] += "@" + version
+		}
+		res.Result = strings.Join(res.Bundle, " ")
+		return
+	}
+	res.Result += "@" + version
+}
+
+// expandBundle returns the packages of the bundle alias that arg, which
+// begins with alias, expands to, and the version requested in arg, if any. The
+// version applies to every package in the bundle.
+func expandBundle(arg, alias string, entry aliasEntry) ([]string, string, error) {
+	rest := arg[len(alias):]
+	if rest != "" && rest[0] != '@' {
+		return nil, "", resolveErrorf(errMisusedAlias, "alias %q is a bundle and cannot be used with a subpath", alias)
+	}
+	if rest == "@" {
+		return nil, "", resolveErrorf(errInvalidVersion, "missing version after @ in %q", arg)
+	}
+	bundle := make([]string, len(entry.Bundle))
+	for i, pkg := range entry.Bundle {
+		bundle[i] = pkg + rest
+	}
+	return bundle, strings.TrimPrefix(rest, "@"), nil
+}
+
+// expandAlias rewrites arg, which begins with alias, by replacing alias with
+// the package of its definition entry.
+func expandAlias(arg, alias string, entry aliasEntry) (expansion, error) {
+	var exp expansion
+	pkg := entry.Package
+	if isPathAlias(pkg) {
+		return expandPathAlias(arg, alias, pkg)
+	}
+	if hasScheme(pkg) {
+		// URLs and other scheme-prefixed values may contain @ and /v
+		// themselves, so the rest of the argument is appended as is.
+		tracef("%q has a scheme; appending %q as is", pkg, arg[len(alias):])
+		exp.result = pkg + arg[len(alias):]
+		return exp, nil
+	}
+
+	// If the user is requesting a specific version, extract it. The
+	// version is opaque to ago, so queries such as @patch, @upgrade and
+	// @none are passed through as they are.
+	var version string
+	if idx := strings.LastIndex(arg, "@"); idx != -1 {
+		version = arg[idx:]
+		arg = arg[:idx]
+		exp.version = version[1:]
+		if exp.version == "" {
+			return exp, resolveErrorf(errInvalidVersion, "missing version after @ in %q", arg+version)
+		}
+		tracef("split off version %q, leaving %q", exp.version, arg)
+	}
+
+	// The rest of the argument is the package path within the aliased
+	// package. Namespace aliases may or may not end in a slash, so the
+	// separating slash is dropped here and added back when joining. Only
+	// that one slash is dropped: the rest of the path is the user's, and
+	// is passed through untouched, even if it starts with another slash.
+	pkgPath := strings.TrimPrefix(arg, alias)
+	if !strings.HasSuffix(alias, "/") {
+		pkgPath = strings.TrimPrefix(pkgPath, "/")
+	}
+
+	// If the package path starts with a major version, then we need
+	// to strip it off and replace it with the aliased package path.
+	// Major versions are a module concept, so in GOPATH mode the
+	// package path is used as is. Aliases with RawMajor set opt out
+	// too, for modules that don't follow the usual versioning.
+	var major string
+	if first, rest, _ := strings.Cut(pkgPath, "/"); !cfg.GopathMode && !entry.RawMajor && len(first) > 1 && first[0] == 'v' {
+		if _, err := strconv.Atoi(first[1:]); err == nil {
+			major = "/" + first
+			exp.major = first
+			pkgPath = rest
+			tracef("requested major version %s, leaving subpath %q", first, pkgPath)
+		}
+	}
+
+	// An alias to a package pattern, such as github.com/foo/bar/..., already
+	// covers every package within it, so a subpath makes no sense. The
+	// pattern is set aside so the major version handling below only sees
+	// the module path.
+	pattern := strings.HasSuffix(pkg, "/...")
+	if pattern {
+		if pkgPath != "" && pkgPath != "..." {
+			return exp, resolveErrorf(errMisusedAlias, "alias %q is a package pattern and cannot be used with a subpath", alias)
+		}
+		pkg = strings.TrimSuffix(pkg, "/...")
+		pkgPath = "..."
+		tracef("%q is a package pattern", entry.Package)
+	}
+
+	// If the user has requested a specific major version, and the
+	// aliased package path already contains a major version, then
+	// we need to strip it off and replace it with the requested
+	// major version. Unless the requested major version < 2, in
+	// which case we just strip it off.
+	if major != "" {
+		// Strip off the major version.
+		if idx := strings.LastIndex(pkg, "/v"); idx != -1 {
+			if _, err := strconv.Atoi(pkg[idx+2:]); err == nil {
+				pkg = pkg[:idx]
+			}
+		}
+
+		// If the requested major version is < 2, then set it to
+		// the empty string.
+		if len(major) == 3 && (major[2] == '0' || major[2] == '1') {
+			major = ""
+		}
+		tracef("replacing the major version of %q with %q", entry.Package, major)
+	}
+
+	exp.result = joinPath(pkg+major, pkgPath) + version
+	return exp, nil
+}
+
+// joinPath joins an aliased package path and a package path within it with
+// a slash, unless pkg already ends in one. Neither path is otherwise changed,
+// so slashes the user wrote, such as in a vanity import path, are kept.
+func joinPath(pkg, pkgPath string) string {
+	if pkgPath == "" || strings.HasSuffix(pkg, "/") {
+		return pkg + pkgPath
+	}
+	return pkg + "/" + pkgPath
+}
+
+// hasScheme reports whether pkg, the package of an alias, begins with a scheme
+// such as "https://" or "git::", rather than being an import path. A Windows
+// drive letter is not a scheme.
+func hasScheme(pkg string) bool {
+	if hasDriveLetter(pkg) {
+		return false
+	}
+	end := strings.IndexByte(pkg, '/')
+	if end == -1 {
+		end = len(pkg)
+	}
+	return strings.Contains(pkg[:end], ":")
+}
+
+// isPathAlias reports whether pkg, the package of an alias, is a directory
+// path rather than an import path. Windows paths, with backslashes or a drive
+// letter, are recognized on every system, so that an aliases file means the
+// same thing everywhere.
+func isPathAlias(pkg string) bool {
+	switch pkg {
+	case ".", "..", "~":
+		return true
+	}
+	for _, prefix := range []string{"./", "../", "~/", "/", `.\`, `..\`, `~\`, `\`} {
+		if strings.HasPrefix(pkg, prefix) {
+			return true
+		}
+	}
+	return hasDriveLetter(pkg) || filepath.IsAbs(pkg)
+}
+
+// hasDriveLetter reports whether path begins with a Windows drive letter, as
+// in C:\src or C:/src.
+func hasDriveLetter(path string) bool {
+	if len(path) < 3 || path[1] != ':' || (path[2] != '\\' && path[2] != '/') {
+		return false
+	}
+	c := path[0] | 0x20 // lower case
+	return c >= 'a' && c <= 'z'
+}
+
+// isAbsDir reports whether dir, the directory of a path alias, is absolute,
+// on this system or on Windows.
+func isAbsDir(dir string) bool {
+	return filepath.IsAbs(dir) || strings.HasPrefix(dir, "/") || strings.HasPrefix(dir, `\`) || hasDriveLetter(dir)
+}
+
+// expandPathAlias rewrites arg, which begins with alias, by replacing alias
+// with its directory dir. A leading ~ in dir is replaced with the user's home
+// directory. Relative directories are resolved against the module root, if
+// one is configured, rather than the working directory. The rest of arg is
+// joined to dir with backslashes if dir uses them, and slashes otherwise.
+func expandPathAlias(arg, alias, dir string) (expansion, error) {
+	var exp expansion
+	if strings.Contains(arg[len(alias):], "@") {
+		return exp, resolveErrorf(errMisusedAlias, "alias %q is a directory and cannot be used with a version", alias)
+	}
+	if dir == "~" || strings.HasPrefix(dir, "~/") || strings.HasPrefix(dir, `~\`) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return exp, fmt.Errorf("expand ~ in alias %q: %w", alias, err)
+		}
+		dir = home + dir[1:]
+	}
+	if !isAbsDir(dir) && cfg.ModuleRoot != "" {
+		dir = filepath.Join(cfg.ModuleRoot, dir)
+	}
+
+	tracef("%q is a directory alias for %q", alias, dir)
+	exp.result = dir
+	if sub := strings.TrimLeft(arg[len(alias):], `/\`); sub != "" {
+		sep := "/"
+		if strings.Contains(dir, `\`) {
+			sep = `\`
+			sub = strings.ReplaceAll(sub, "/", sep)
+		}
+		exp.result = strings.TrimRight(dir, `/\`) + sep + sub
+	}
+	return exp, nil
+}
@@ -0,0 +1,760 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestJoinPath(t *testing.T) {
+	tests := []struct {
+		pkg, pkgPath, want string
+	}{
+		{"github.com/org", "repo", "github.com/org/repo"},
+		{"github.com/org/", "repo", "github.com/org/repo"},
+		{"github.com/org", "", "github.com/org"},
+		{"github.com/org/", "", "github.com/org/"},
+	}
+	for _, tt := range tests {
+		if got := joinPath(tt.pkg, tt.pkgPath); got != tt.want {
+			t.Errorf("joinPath(%q, %q) = %q, want %q", tt.pkg, tt.pkgPath, got, tt.want)
+		}
+	}
+}
+
+func TestExpandNamespaceSlashes(t *testing.T) {
+	// Every combination of a trailing slash on the alias name and on its
+	// package expands to exactly one slash before the rest of the path.
+	tests := []struct {
+		alias, pkg string
+	}{
+		{"gh", "github.com/org"},
+		{"gh", "github.com/org/"},
+		{"gh/", "github.com/org"},
+		{"gh/", "github.com/org/"},
+	}
+	for _, tt := range tests {
+		aliases := map[string]aliasEntry{tt.alias: {Package: tt.pkg}}
+		got, err := expandArg(aliases, "gh/repo")
+		if err != nil {
+			t.Errorf("alias %q -> %q: expandArg(gh/repo) failed: %v", tt.alias, tt.pkg, err)
+			continue
+		}
+		if want := "github.com/org/repo"; got != want {
+			t.Errorf("alias %q -> %q: expandArg(gh/repo) = %q, want %q", tt.alias, tt.pkg, got, want)
+		}
+	}
+}
+
+func TestResolveCycles(t *testing.T) {
+	tests := []struct {
+		name    string
+		aliases map[string]aliasEntry
+		arg     string
+		want    string
+	}{
+		{
+			name:    "self",
+			aliases: map[string]aliasEntry{"foo": {Package: "foo"}},
+			arg:     "foo",
+			want:    "alias cycle detected: foo -> foo",
+		},
+		{
+			name:    "self with subpath",
+			aliases: map[string]aliasEntry{"foo": {Package: "foo/bar"}},
+			arg:     "foo",
+			want:    "alias cycle detected: foo -> foo",
+		},
+		{
+			name:    "indirect",
+			aliases: map[string]aliasEntry{"a": {Package: "b"}, "b": {Package: "c"}, "c": {Package: "a"}},
+			arg:     "a",
+			want:    "alias cycle detected: a -> b -> c -> a",
+		},
+	}
+	for _, tt := range tests {
+		_, err := resolve(tt.aliases, tt.arg)
+		if err == nil {
+			t.Errorf("%s: resolve(%q) succeeded, want a cycle error", tt.name, tt.arg)
+			continue
+		}
+		if err.Error() != tt.want {
+			t.Errorf("%s: resolve(%q) error = %q, want %q", tt.name, tt.arg, err, tt.want)
+		}
+	}
+}
+
+func TestResolveChain(t *testing.T) {
+	aliases := map[string]aliasEntry{
+		"a": {Package: "b/x"},
+		"b": {Package: "github.com/foo/bar"},
+	}
+	res, err := resolve(aliases, "a")
+	if err != nil {
+		t.Fatalf("resolve(a) failed: %v", err)
+	}
+	if want := "github.com/foo/bar/x"; res.Result != want {
+		t.Errorf("resolve(a) = %q, want %q", res.Result, want)
+	}
+	if len(res.Chain) != 2 || res.Chain[0] != "a" || res.Chain[1] != "b" {
+		t.Errorf("resolve(a) chain = %v, want [a b]", res.Chain)
+	}
+}
+
+func TestResolveRawMajor(t *testing.T) {
+	tests := []struct {
+		rawMajor bool
+		arg      string
+		want     string
+	}{
+		{false, "foo/v3", "github.com/foo/bar/v3"},
+		{false, "foo/v3/cmd@v3.1.0", "github.com/foo/bar/v3/cmd@v3.1.0"},
+		{false, "foo/v1", "github.com/foo/bar"},
+		{true, "foo/v3", "github.com/foo/bar/v2/v3"},
+		{true, "foo/v3/cmd@v3.1.0", "github.com/foo/bar/v2/v3/cmd@v3.1.0"},
+		{true, "foo/v1", "github.com/foo/bar/v2/v1"},
+	}
+	for _, tt := range tests {
+		aliases := map[string]aliasEntry{"foo": {Package: "github.com/foo/bar/v2", RawMajor: tt.rawMajor}}
+		got, err := expandArg(aliases, tt.arg)
+		if err != nil {
+			t.Errorf("rawMajor %v: expandArg(%q) failed: %v", tt.rawMajor, tt.arg, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("rawMajor %v: expandArg(%q) = %q, want %q", tt.rawMajor, tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestResolveExactMatch(t *testing.T) {
+	// Exact aliases, and every alias with the exact match setting, only
+	// match the alias name itself, optionally followed by a version.
+	tests := []struct {
+		arg    string
+		prefix string
+		exact  string
+	}{
+		{"foo", "github.com/foo/bar", "github.com/foo/bar"},
+		{"foo@v1.2.3", "github.com/foo/bar@v1.2.3", "github.com/foo/bar@v1.2.3"},
+		{"foo/cmd", "github.com/foo/bar/cmd", "foo/cmd"},
+		{"foobar", "github.com/foo/bar/bar", "foobar"},
+	}
+	saved := cfg
+	t.Cleanup(func() { cfg = saved })
+	for _, tt := range tests {
+		for _, mode := range []struct {
+			name           string
+			exact, setting bool
+			want           string
+		}{
+			{"prefix", false, false, tt.prefix},
+			{"exact alias", true, false, tt.exact},
+			{"exact setting", false, true, tt.exact},
+		} {
+			cfg.ExactMatch = mode.setting
+			aliases := map[string]aliasEntry{"foo": {Package: "github.com/foo/bar", ExactMatch: mode.exact}}
+			got, err := expandArg(aliases, tt.arg)
+			if err != nil {
+				t.Errorf("%s: expandArg(%q) failed: %v", mode.name, tt.arg, err)
+				continue
+			}
+			if got != mode.want {
+				t.Errorf("%s: expandArg(%q) = %q, want %q", mode.name, tt.arg, got, mode.want)
+			}
+		}
+	}
+}
+
+func TestResolveToolchain(t *testing.T) {
+	// An alias named go or toolchain never rewrites the pseudo-modules.
+	aliases := map[string]aliasEntry{
+		"go":        {Package: "github.com/go/go"},
+		"toolchain": {Package: "github.com/foo/toolchain"},
+	}
+	for _, arg := range []string{"go", "go@1.22", "go@latest", "toolchain@go1.22.1", "toolchain@none"} {
+		got, err := expandArg(aliases, arg)
+		if err != nil {
+			t.Errorf("expandArg(%q) failed: %v", arg, err)
+			continue
+		}
+		if got != arg {
+			t.Errorf("expandArg(%q) = %q, want it unchanged", arg, got)
+		}
+	}
+	// Only the pseudo-modules themselves are left alone.
+	if got, err := expandArg(aliases, "go/cmd"); err != nil || got != "github.com/go/go/cmd" {
+		t.Errorf("expandArg(go/cmd) = %q, %v, want github.com/go/go/cmd", got, err)
+	}
+}
+
+func TestTakesValue(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want bool
+	}{
+		{"-o", true},
+		{"--o", true},
+		{"-o=bin/foo", false},
+		{"-ldflags", true},
+		{"-tags", true},
+		{"-vet", true},
+		{"-blockprofilerate", true},
+		{"-memprofilerate", true},
+		{"-mutexprofilefraction", true},
+		{"-v", false},
+		{"-race", false},
+		{"o", false},
+	}
+	for _, tt := range tests {
+		if got := takesValue(tt.arg); got != tt.want {
+			t.Errorf("takesValue(%q) = %v, want %v", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestExpandWindowsPaths(t *testing.T) {
+	// Windows paths are recognized on every system, so these run everywhere.
+	tests := []struct {
+		dir, arg, want string
+	}{
+		{`C:\src\mylib`, "lib", `C:\src\mylib`},
+		{`C:\src\mylib`, "lib/sub/pkg", `C:\src\mylib\sub\pkg`},
+		{`C:\src\mylib\`, `lib\sub`, `C:\src\mylib\sub`},
+		{`c:/src/mylib`, "lib/sub", "c:/src/mylib/sub"},
+		{`..\mylib`, "lib/sub", `..\mylib\sub`},
+	}
+	for _, tt := range tests {
+		aliases := map[string]aliasEntry{"lib": {Package: tt.dir}}
+		got, err := expandArg(aliases, tt.arg)
+		if err != nil {
+			t.Errorf("alias lib -> %q: expandArg(%q) failed: %v", tt.dir, tt.arg, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("alias lib -> %q: expandArg(%q) = %q, want %q", tt.dir, tt.arg, got, tt.want)
+		}
+	}
+
+	// The colon of a drive letter is neither a scheme nor the start of a
+	// version, and a version after a directory is an error.
+	aliases := map[string]aliasEntry{"lib": {Package: `C:\src\mylib`}}
+	if _, err := expandArg(aliases, "lib@v1.0.0"); err == nil {
+		t.Errorf("expandArg(lib@v1.0.0) succeeded, want an error for a directory alias")
+	}
+}
+
+func TestHasDriveLetter(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{`C:\src`, true},
+		{`c:/src`, true},
+		{`C:`, false},
+		{`C:src`, false},
+		{`1:\src`, false},
+		{"https://example.com", false},
+		{"git::https://example.com", false},
+	}
+	for _, tt := range tests {
+		if got := hasDriveLetter(tt.path); got != tt.want {
+			t.Errorf("hasDriveLetter(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+		if tt.want && (hasScheme(tt.path) || !isPathAlias(tt.path)) {
+			t.Errorf("%q is not treated as a directory path", tt.path)
+		}
+	}
+}
+
+func TestMatchAliasDots(t *testing.T) {
+	aliases := map[string]aliasEntry{
+		"k8s":    {Package: "github.com/foo/k8s"},
+		"k8s.io": {Package: "github.com/kubernetes/client-go"},
+		"x.":     {Package: "golang.org/x/"},
+		"gh/org": {Package: "github.com/org"},
+	}
+	tests := []struct {
+		arg, want string
+	}{
+		{"k8s", "github.com/foo/k8s"},
+		{"k8s/cmd", "github.com/foo/k8s/cmd"},
+		{"k8s.io", "github.com/kubernetes/client-go"},
+		{"k8s.io/tools@v0.30.0", "github.com/kubernetes/client-go/tools@v0.30.0"},
+		{"x.tools", "golang.org/x/tools"},
+		{"gh/org/repo", "github.com/org/repo"},
+	}
+	for _, tt := range tests {
+		got, err := expandArg(aliases, tt.arg)
+		if err != nil {
+			t.Errorf("expandArg(%q) failed: %v", tt.arg, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("expandArg(%q) = %q, want %q", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestResolvePseudoPackages(t *testing.T) {
+	// Aliases named after the meta-packages never rewrite them.
+	aliases := map[string]aliasEntry{}
+	for _, name := range []string{"all", "std", "cmd", "main", "tool"} {
+		aliases[name] = aliasEntry{Package: "github.com/foo/" + name}
+	}
+	for name := range aliases {
+		if !isPseudoPackage(name) {
+			t.Errorf("isPseudoPackage(%q) = false, want true", name)
+		}
+		res, err := resolve(aliases, name)
+		if err != nil {
+			t.Errorf("resolve(%q) failed: %v", name, err)
+			continue
+		}
+		if res.Matched || res.Result != name {
+			t.Errorf("resolve(%q) = %q, want it unchanged", name, res.Result)
+		}
+	}
+	// Only the meta-packages themselves are left alone.
+	if got, err := expandArg(aliases, "cmd/foo"); err != nil || got != "github.com/foo/cmd/foo" {
+		t.Errorf("expandArg(cmd/foo) = %q, %v, want github.com/foo/cmd/foo", got, err)
+	}
+}
+
+func TestResolveVersionQueries(t *testing.T) {
+	aliases := map[string]aliasEntry{"foo": {Package: "github.com/foo/bar", Version: "v1.2.3"}}
+	for _, query := range []string{"patch", "none", "upgrade", "latest", "master", "<v1.5"} {
+		arg := "foo@" + query
+		res, err := resolve(aliases, arg)
+		if err != nil {
+			t.Errorf("resolve(%q) failed: %v", arg, err)
+			continue
+		}
+		if want := "github.com/foo/bar@" + query; res.Result != want {
+			t.Errorf("resolve(%q) = %q, want %q", arg, res.Result, want)
+		}
+		// A query in the argument takes precedence over the pinned version.
+		applyPinnedVersion(aliases, &res)
+		if res.Version != query {
+			t.Errorf("resolve(%q) version = %q, want %q", arg, res.Version, query)
+		}
+	}
+}
+
+func TestEndsPackages(t *testing.T) {
+	tests := []struct {
+		cmd, arg string
+		want     bool
+	}{
+		{"run", "--", true},
+		{"test", "--", true},
+		{"build", "--", true},
+		{"test", "-args", true},
+		{"test", "--args", true},
+		{"run", "-args", false},
+		{"build", "--args", false},
+		{"test", "-v", false},
+		{"run", "foo", false},
+	}
+	for _, tt := range tests {
+		if got := endsPackages(tt.cmd, tt.arg); got != tt.want {
+			t.Errorf("endsPackages(%q, %q) = %v, want %v", tt.cmd, tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestExpandPreservesSubpathSlashes(t *testing.T) {
+	// Only the slash ago adds between the package and the subpath is
+	// normalized. The subpath and version query are the user's.
+	tests := []struct {
+		pkg, arg, want string
+	}{
+		{"github.com/org/foo", "foo//x%2F/y@v1.0.0", "github.com/org/foo//x%2F/y@v1.0.0"},
+		{"github.com/org/foo/", "foo/x//y", "github.com/org/foo/x//y"},
+		{"github.com/org/foo", "foo/x/@v1.0.0", "github.com/org/foo/x/@v1.0.0"},
+		{"example.com//vanity", "foo/x", "example.com//vanity/x"},
+	}
+	for _, tt := range tests {
+		aliases := map[string]aliasEntry{"foo": {Package: tt.pkg}}
+		got, err := expandArg(aliases, tt.arg)
+		if err != nil {
+			t.Errorf("alias foo -> %q: expandArg(%q) failed: %v", tt.pkg, tt.arg, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("alias foo -> %q: expandArg(%q) = %q, want %q", tt.pkg, tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestResolveErrorKinds(t *testing.T) {
+	aliases := map[string]aliasEntry{
+		"loop":    {Package: "loop"},
+		"tools":   {Bundle: []string{"golang.org/x/tools/gopls", "golang.org/x/tools/cmd/goimports"}},
+		"local":   {Package: "./local"},
+		"pattern": {Package: "github.com/foo/bar/..."},
+		"foo":     {Package: "github.com/foo/bar"},
+	}
+	tests := []struct {
+		arg  string
+		kind error
+	}{
+		{"loop", errAliasCycle},
+		{"tools/gopls", errMisusedAlias},
+		{"local@v1.0.0", errMisusedAlias},
+		{"pattern/cmd", errMisusedAlias},
+		{"foo@", errInvalidVersion},
+		{"tools@", errInvalidVersion},
+	}
+	kinds := []error{errAliasCycle, errMisusedAlias, errInvalidVersion}
+	for _, tt := range tests {
+		_, err := resolve(aliases, tt.arg)
+		if err == nil {
+			t.Errorf("resolve(%q) succeeded, want %v", tt.arg, tt.kind)
+			continue
+		}
+		for _, kind := range kinds {
+			if got, want := errors.Is(err, kind), kind == tt.kind; got != want {
+				t.Errorf("resolve(%q) error %q: errors.Is(err, %v) = %v, want %v", tt.arg, err, kind, got, want)
+			}
+		}
+		if code := resolveExitCode(err); code != exitDataErr {
+			t.Errorf("resolveExitCode(%q) = %d, want %d", err, code, exitDataErr)
+		}
+	}
+	if code := resolveExitCode(errors.New("other")); code != exitSoftware {
+		t.Errorf("resolveExitCode of another error = %d, want %d", code, exitSoftware)
+	}
+}
+
+func TestExpandPatterns(t *testing.T) {
+	tests := []struct {
+		pkg, arg, want string
+	}{
+		{"github.com/foo/bar", "foo/...", "github.com/foo/bar/..."},
+		{"github.com/foo/bar", "foo/v2/...", "github.com/foo/bar/v2/..."},
+		{"github.com/foo/bar/v3", "foo/v2/...", "github.com/foo/bar/v2/..."},
+		{"github.com/foo/bar/...", "foo", "github.com/foo/bar/..."},
+		{"github.com/foo/bar/...", "foo/...", "github.com/foo/bar/..."},
+		{"github.com/foo/bar/...", "foo/v2", "github.com/foo/bar/v2/..."},
+		{"github.com/foo/bar/v2/...", "foo/v1", "github.com/foo/bar/..."},
+		{"github.com/foo/bar/...", "foo@v1.2.3", "github.com/foo/bar/...@v1.2.3"},
+	}
+	for _, tt := range tests {
+		aliases := map[string]aliasEntry{"foo": {Package: tt.pkg}}
+		got, err := expandArg(aliases, tt.arg)
+		if err != nil {
+			t.Errorf("alias foo -> %q: expandArg(%q) failed: %v", tt.pkg, tt.arg, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("alias foo -> %q: expandArg(%q) = %q, want %q", tt.pkg, tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestExpandGopathMode(t *testing.T) {
+	// Major versions are a module concept: in GOPATH mode, the argument is
+	// substituted as is.
+	tests := []struct {
+		arg, module, gopath string
+	}{
+		{"foo", "github.com/foo/bar/v2", "github.com/foo/bar/v2"},
+		{"foo/cmd", "github.com/foo/bar/v2/cmd", "github.com/foo/bar/v2/cmd"},
+		{"foo/v3/cmd", "github.com/foo/bar/v3/cmd", "github.com/foo/bar/v2/v3/cmd"},
+		{"foo/v1", "github.com/foo/bar", "github.com/foo/bar/v2/v1"},
+	}
+	saved := cfg
+	t.Cleanup(func() { cfg = saved })
+	aliases := map[string]aliasEntry{"foo": {Package: "github.com/foo/bar/v2"}}
+	for _, tt := range tests {
+		for _, gopathMode := range []bool{false, true} {
+			cfg.GopathMode = gopathMode
+			want := tt.module
+			if gopathMode {
+				want = tt.gopath
+			}
+			got, err := expandArg(aliases, tt.arg)
+			if err != nil {
+				t.Errorf("gopathMode %v: expandArg(%q) failed: %v", gopathMode, tt.arg, err)
+				continue
+			}
+			if got != want {
+				t.Errorf("gopathMode %v: expandArg(%q) = %q, want %q", gopathMode, tt.arg, got, want)
+			}
+		}
+	}
+}
+
+func TestExpandRelativePathAlias(t *testing.T) {
+	saved := cfg
+	t.Cleanup(func() { cfg = saved })
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	root := t.TempDir()
+	aliases := map[string]aliasEntry{
+		"lib": {Package: "./mylib"},
+		"abs": {Package: filepath.Join(root, "abs")},
+	}
+	for _, dir := range []string{t.TempDir(), t.TempDir()} {
+		if err := os.Chdir(dir); err != nil {
+			t.Fatal(err)
+		}
+		// With a module root, relative directories resolve against it
+		// wherever ago is run.
+		cfg.ModuleRoot = root
+		if got, err := expandArg(aliases, "lib/sub"); err != nil || got != filepath.Join(root, "mylib")+"/sub" {
+			t.Errorf("in %s with module root %s: expandArg(lib/sub) = %q, %v, want %s/sub", dir, root, got, err, filepath.Join(root, "mylib"))
+		}
+		// Without one, they are left relative, so go resolves them
+		// against the working directory.
+		cfg.ModuleRoot = ""
+		if got, err := expandArg(aliases, "lib/sub"); err != nil || got != "./mylib/sub" {
+			t.Errorf("in %s: expandArg(lib/sub) = %q, %v, want ./mylib/sub", dir, got, err)
+		}
+		// Absolute directories are unaffected either way.
+		for _, moduleRoot := range []string{root, ""} {
+			cfg.ModuleRoot = moduleRoot
+			if got, err := expandArg(aliases, "abs"); err != nil || got != filepath.Join(root, "abs") {
+				t.Errorf("in %s with module root %q: expandArg(abs) = %q, %v, want %s", dir, moduleRoot, got, err, filepath.Join(root, "abs"))
+			}
+		}
+	}
+}
+
+func TestExpandSchemeTargets(t *testing.T) {
+	// Values with a scheme may contain @ and /v themselves, so the rest of
+	// the argument is appended verbatim, with no version or major handling.
+	tests := []struct {
+		pkg, arg, want string
+	}{
+		{"git::https://host/repo.git", "repo", "git::https://host/repo.git"},
+		{"git::https://host/repo.git", "repo@v1.2.3", "git::https://host/repo.git@v1.2.3"},
+		{"git::https://host/repo.git", "repo/v2", "git::https://host/repo.git/v2"},
+		{"https://user@host/repo/v2", "repo", "https://user@host/repo/v2"},
+		{"https://user@host/repo/v2", "repo/v3/cmd", "https://user@host/repo/v2/v3/cmd"},
+	}
+	for _, tt := range tests {
+		aliases := map[string]aliasEntry{"repo": {Package: tt.pkg}}
+		got, err := expandArg(aliases, tt.arg)
+		if err != nil {
+			t.Errorf("alias repo -> %q: expandArg(%q) failed: %v", tt.pkg, tt.arg, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("alias repo -> %q: expandArg(%q) = %q, want %q", tt.pkg, tt.arg, got, tt.want)
+		}
+	}
+	for _, pkg := range []string{"git::https://host/repo.git", "https://host/repo", "ssh://git@host/repo"} {
+		if !hasScheme(pkg) {
+			t.Errorf("hasScheme(%q) = false, want true", pkg)
+		}
+	}
+	for _, pkg := range []string{"github.com/foo/bar", "example.com/foo@v1:x", `C:\src`} {
+		if hasScheme(pkg) {
+			t.Errorf("hasScheme(%q) = true, want false", pkg)
+		}
+	}
+}
+
+func TestExpandHomeDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	tests := []struct {
+		dir, arg, want string
+	}{
+		{"~", "lib", home},
+		{"~", "lib/x", home + "/x"},
+		{"~/src/mylib", "lib", home + "/src/mylib"},
+		{"~/src/mylib", "lib/x", home + "/src/mylib/x"},
+		// Only a leading ~ on its own is the home directory.
+		{"~user/src", "lib", "~user/src"},
+		{"./a~/b", "lib", "./a~/b"},
+	}
+	for _, tt := range tests {
+		aliases := map[string]aliasEntry{"lib": {Package: tt.dir}}
+		got, err := expandArg(aliases, tt.arg)
+		if err != nil {
+			t.Errorf("alias lib -> %q: expandArg(%q) failed: %v", tt.dir, tt.arg, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("alias lib -> %q: expandArg(%q) = %q, want %q", tt.dir, tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestPrintExpanded(t *testing.T) {
+	// ago which and ago expand write exactly the resolved values, one per
+	// line, and nothing else.
+	aliases := map[string]aliasEntry{
+		"foo":   {Package: "github.com/foo/bar"},
+		"tools": {Bundle: []string{"golang.org/x/tools/gopls", "golang.org/x/tools/cmd/goimports"}},
+	}
+	var buf bytes.Buffer
+	used, err := printExpanded(&buf, aliases, []string{"foo", "foo/cmd@v1.2.3", "example.com/other"})
+	if err != nil {
+		t.Fatalf("printExpanded failed: %v", err)
+	}
+	if got, want := buf.String(), "github.com/foo/bar\ngithub.com/foo/bar/cmd@v1.2.3\nexample.com/other\n"; got != want {
+		t.Errorf("printExpanded wrote %q, want %q", got, want)
+	}
+	if want := []string{"foo", "foo"}; !reflect.DeepEqual(used, want) {
+		t.Errorf("printExpanded used %q, want %q", used, want)
+	}
+
+	// A bundle is several packages, not one, so it fails with a data
+	// error, after the values before it.
+	buf.Reset()
+	_, err = printExpanded(&buf, aliases, []string{"foo", "tools", "foo"})
+	if !errors.Is(err, errMisusedAlias) {
+		t.Errorf("printExpanded of a bundle error = %v, want %v", err, errMisusedAlias)
+	}
+	if code := resolveExitCode(err); code != exitDataErr {
+		t.Errorf("resolveExitCode(%v) = %d, want %d", err, code, exitDataErr)
+	}
+	if got, want := buf.String(), "github.com/foo/bar\n"; got != want {
+		t.Errorf("printExpanded wrote %q, want %q", got, want)
+	}
+}
+
+func TestExpandArgs(t *testing.T) {
+	aliases := map[string]aliasEntry{
+		"foo": {Package: "github.com/foo/bar"},
+		"bin": {Package: "github.com/foo/bin"},
+	}
+	tests := []struct {
+		cmd  string
+		args []string
+		want []string
+	}{
+		{"build", []string{"-o", "bin/foo", "foo/cmd"}, []string{"-o", "bin/foo", "github.com/foo/bar/cmd"}},
+		{"build", []string{"-o=bin/foo", "foo/cmd"}, []string{"-o=bin/foo", "github.com/foo/bar/cmd"}},
+		{"build", []string{"-ldflags", "-X foo.x=y", "-tags", "foo", "foo"}, []string{"-ldflags", "-X foo.x=y", "-tags", "foo", "github.com/foo/bar"}},
+		{"test", []string{"-v", "-run", "foo", "foo/...", "bin"}, []string{"-v", "-run", "foo", "github.com/foo/bar/...", "github.com/foo/bin"}},
+		{"generate", []string{"-run", "X", "foo/..."}, []string{"-run", "X", "github.com/foo/bar/..."}},
+		{"generate", []string{"-run", "bin", "-skip=foo", "foo/...", "bin"}, []string{"-run", "bin", "-skip=foo", "github.com/foo/bar/...", "github.com/foo/bin"}},
+		{"build", []string{"foo", "--", "foo", "bin"}, []string{"github.com/foo/bar", "--", "foo", "bin"}},
+		{"test", []string{"-v", "foo", "-args", "foo", "-o", "bin"}, []string{"-v", "github.com/foo/bar", "-args", "foo", "-o", "bin"}},
+		{"test", []string{"foo", "--args", "bin"}, []string{"github.com/foo/bar", "--args", "bin"}},
+		{"vet", []string{"foo", "-args", "bin"}, []string{"github.com/foo/bar", "-args", "github.com/foo/bin"}},
+		{"get", []string{"-insecure", "foo"}, []string{"-insecure", "github.com/foo/bar"}},
+		{"get", []string{"-u", "-insecure", "foo@v1.2.3", "bin"}, []string{"-u", "-insecure", "github.com/foo/bar@v1.2.3", "github.com/foo/bin"}},
+	}
+	for _, tt := range tests {
+		got, err := expandArgs(aliases, tt.cmd, tt.args, nil)
+		if err != nil {
+			t.Errorf("expandArgs(%s %q) failed: %v", tt.cmd, tt.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expandArgs(%s %q) = %q, want %q", tt.cmd, tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestExpandArgsResolutions(t *testing.T) {
+	aliases := map[string]aliasEntry{
+		"foo":   {Package: "github.com/foo/bar", Version: "v1.2.3"},
+		"tools": {Bundle: []string{"golang.org/x/tools/gopls", "golang.org/x/tools/cmd/goimports"}},
+	}
+	var seen []string
+	got, err := expandArgs(aliases, "get", []string{"-u", "foo", "tools@latest", "other"}, func(res *resolution) {
+		seen = append(seen, res.Arg)
+		if res.Alias == "foo" {
+			res.Result = "changed"
+		}
+	})
+	if err != nil {
+		t.Fatalf("expandArgs failed: %v", err)
+	}
+	// Pinned versions apply to get, bundles expand to their packages, and
+	// changes made by each are substituted.
+	want := []string{"-u", "changed", "golang.org/x/tools/gopls@latest", "golang.org/x/tools/cmd/goimports@latest", "other"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expandArgs = %q, want %q", got, want)
+	}
+	if want := []string{"foo", "tools@latest", "other"}; !reflect.DeepEqual(seen, want) {
+		t.Errorf("expandArgs called each with %q, want %q", seen, want)
+	}
+
+	// Only get and install apply pinned versions.
+	if got, err := expandArgs(aliases, "build", []string{"foo"}, nil); err != nil || !reflect.DeepEqual(got, []string{"github.com/foo/bar"}) {
+		t.Errorf("expandArgs(build foo) = %q, %v, want [github.com/foo/bar]", got, err)
+	}
+	if got, err := expandArgs(aliases, "install", []string{"foo"}, nil); err != nil || !reflect.DeepEqual(got, []string{"github.com/foo/bar@v1.2.3"}) {
+		t.Errorf("expandArgs(install foo) = %q, %v, want [github.com/foo/bar@v1.2.3]", got, err)
+	}
+	if _, err := expandArgs(aliases, "build", []string{"tools/x"}, nil); !errors.Is(err, errMisusedAlias) {
+		t.Errorf("expandArgs(build tools/x) error = %v, want %v", err, errMisusedAlias)
+	}
+}
+
+func TestPathAliasesForWorkUse(t *testing.T) {
+	// go work use takes directories, so only path aliases expand there.
+	aliases := map[string]aliasEntry{
+		"mylib": {Package: "../mylib"},
+		"abs":   {Package: "/src/abs"},
+		"foo":   {Package: "github.com/foo/bar"},
+		"tools": {Bundle: []string{"./a", "./b"}},
+	}
+	dirs := pathAliases(aliases)
+	tests := []struct {
+		arg, want string
+	}{
+		{"mylib", "../mylib"},
+		{"mylib/sub", "../mylib/sub"},
+		{"abs", "/src/abs"},
+		{"foo", "foo"},
+		{"foo/sub", "foo/sub"},
+		{"tools", "tools"},
+		{"./other", "./other"},
+	}
+	for _, tt := range tests {
+		got, err := expandArg(dirs, tt.arg)
+		if err != nil {
+			t.Errorf("expandArg of path aliases (%q) failed: %v", tt.arg, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("expandArg of path aliases (%q) = %q, want %q", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestResolveDoc(t *testing.T) {
+	aliases := map[string]aliasEntry{
+		"foo":    {Package: "github.com/foo/bar"},
+		"k8s.io": {Package: "github.com/kubernetes/client-go"},
+	}
+	tests := []struct {
+		arg, want string
+	}{
+		{"foo", "github.com/foo/bar"},
+		{"foo.Func", "github.com/foo/bar.Func"},
+		{"foo.Type.Method", "github.com/foo/bar.Type.Method"},
+		{"foo/sub", "github.com/foo/bar/sub"},
+		{"foo/sub.Func", "github.com/foo/bar/sub.Func"},
+		{"k8s.io", "github.com/kubernetes/client-go"},
+		{"k8s.io.Interface", "github.com/kubernetes/client-go.Interface"},
+		{"fmt.Println", "fmt.Println"},
+	}
+	for _, tt := range tests {
+		res, err := resolveDoc(aliases, tt.arg)
+		if err != nil {
+			t.Errorf("resolveDoc(%q) failed: %v", tt.arg, err)
+			continue
+		}
+		if res.Result != tt.want {
+			t.Errorf("resolveDoc(%q) = %q, want %q", tt.arg, res.Result, tt.want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+)
+
+// formatJSON is the format of the aliases file. It is the only format ago
+// reads and writes, but the format setting names it explicitly so that a file
+// in another format is reported as such, rather than as a JSON syntax error.
+const formatJSON = "json"
+
+// aliasFormats are the formats the format setting accepts.
+var aliasFormats = map[string]bool{formatJSON: true}
+
+var (
+	yamlLine = regexp.MustCompile(`^\s*(- |[\w./"'-]+:(\s|$))`)
+	tomlLine = regexp.MustCompile(`^\s*(\[[^\]]+\]\s*$|[\w./"'-]+\s*=)`)
+)
+
+// detectFormat guesses the format of data, the contents of an aliases file,
+// from its first line that isn't blank or a comment: json, yaml or toml. It
+// returns "" if the format can't be told.
+func detectFormat(data []byte) string {
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		switch {
+		case line[0] == '{':
+			return formatJSON
+		case tomlLine.Match(line):
+			return "toml"
+		case yamlLine.Match(line) || bytes.Equal(line, []byte("---")):
+			return "yaml"
+		}
+		return ""
+	}
+	return ""
+}
+
+// checkFormat returns an error if data, the contents of an aliases file, is
+// evidently in another format than the one configured.
+func checkFormat(data []byte) error {
+	if found := detectFormat(data); found != "" && found != cfg.Format {
+		return fmt.Errorf("file holds %s, but the format setting is %s", found, cfg.Format)
+	}
+	return nil
+}
@@ -1,3 +1,10 @@
 module github.com/deitrix/ago
 
 go 1.20
+
+require (
+	golang.org/x/mod v0.17.0
+	golang.org/x/term v0.20.0
+)
+
+require golang.org/x/sys v0.20.0 // indirect
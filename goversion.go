@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// goVersion returns the version of the go command, e.g. "go1.22.3".
+func goVersion() (string, error) {
+	out, err := exec.Command(goBin(), "env", "GOVERSION").Output()
+	if err != nil {
+		return "", fmt.Errorf("go env GOVERSION: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// compareGoVersions compares two Go versions such as "1.21", "go1.21.5" or
+// "go1.22rc1", returning -1, 0 or +1. Pre-release suffixes are ignored.
+func compareGoVersions(a, b string) int {
+	pa, pb := parseGoVersion(a), parseGoVersion(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return +1
+		}
+	}
+	return 0
+}
+
+func parseGoVersion(v string) []int {
+	v = strings.TrimPrefix(v, "go")
+	var parts []int
+	for _, field := range strings.Split(v, ".") {
+		// Drop any pre-release suffix, e.g. the "rc1" in "22rc1".
+		end := strings.IndexFunc(field, func(r rune) bool { return r < '0' || r > '9' })
+		if end == 0 {
+			break
+		}
+		if end > 0 {
+			field = field[:end]
+		}
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+		if end > 0 {
+			break
+		}
+	}
+	return parts
+}
+
+// validGoVersion reports whether v looks like a Go version.
+func validGoVersion(v string) bool {
+	return len(parseGoVersion(v)) > 0
+}
+
+// checkMinGo returns an error if any of the named aliases requires a newer
+// version of Go than the installed go command. installed is looked up with
+// goVersion on first use, and cached in *installed.
+func checkMinGo(aliases map[string]aliasEntry, names []string, installed *string) error {
+	for _, name := range names {
+		minGo := aliases[name].MinGo
+		if minGo == "" {
+			continue
+		}
+		if *installed == "" {
+			v, err := goVersion()
+			if err != nil {
+				return err
+			}
+			*installed = v
+		}
+		if compareGoVersions(*installed, minGo) < 0 {
+			return fmt.Errorf("alias %q requires go %s or later, but the go command is %s", name, strings.TrimPrefix(minGo, "go"), *installed)
+		}
+	}
+	return nil
+}
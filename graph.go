@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// aliasRefs returns, for each alias, the aliases its package or bundled
+// packages refer to, found the way resolve finds chained aliases, sorted by
+// name.
+func aliasRefs(aliases map[string]aliasEntry) map[string][]string {
+	refs := make(map[string][]string)
+	for name, entry := range aliases {
+		pkgs := entry.Bundle
+		if !entry.isBundle() {
+			pkgs = []string{entry.Package}
+		}
+		for _, pkg := range pkgs {
+			if ref, _ := matchAlias(aliases, pkg, true); ref != "" && !containsString(refs[name], ref) {
+				refs[name] = append(refs[name], ref)
+			}
+		}
+		sort.Strings(refs[name])
+	}
+	return refs
+}
+
+// namespace returns the namespace of the alias name, the part of the name up
+// to and including its first slash, or "" if name has none.
+func namespace(name string) string {
+	if idx := strings.IndexByte(name, '/'); idx != -1 && idx < len(name)-1 {
+		return name[:idx+1]
+	}
+	return ""
+}
+
+// namespaces returns the namespaces of groups, aliases grouped by namespace,
+// sorted and leaving out the empty namespace of aliases outside of any.
+func namespaces(groups map[string][]string) []string {
+	var names []string
+	for ns := range groups {
+		if ns != "" {
+			names = append(names, ns)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// graphRoots returns the aliases no other alias refers to, grouped by
+// namespace. Aliases that are only referred to within a cycle are roots too,
+// so that every alias appears in the graph.
+func graphRoots(aliases map[string]aliasEntry, refs map[string][]string) map[string][]string {
+	referenced := make(map[string]bool)
+	for _, to := range refs {
+		for _, ref := range to {
+			referenced[ref] = true
+		}
+	}
+	reached := make(map[string]bool)
+	var reach func(name string)
+	reach = func(name string) {
+		if reached[name] {
+			return
+		}
+		reached[name] = true
+		for _, ref := range refs[name] {
+			reach(ref)
+		}
+	}
+	var roots []string
+	for _, name := range sortedNames(aliases) {
+		if !referenced[name] {
+			roots = append(roots, name)
+			reach(name)
+		}
+	}
+	for _, name := range sortedNames(aliases) {
+		if !reached[name] {
+			roots = append(roots, name)
+			reach(name)
+		}
+	}
+	groups := make(map[string][]string)
+	for _, name := range roots {
+		ns := namespace(name)
+		groups[ns] = append(groups[ns], name)
+	}
+	return groups
+}
+
+// printAliasGraph writes a tree of aliases to w, with the aliases each alias
+// refers to below it. Aliases in a namespace, such as work/api, are grouped
+// under the namespace.
+func printAliasGraph(w io.Writer, aliases map[string]aliasEntry) {
+	refs := aliasRefs(aliases)
+	groups := graphRoots(aliases, refs)
+
+	var printTree func(name, prefix string, path []string)
+	printTree = func(name, prefix string, path []string) {
+		to := refs[name]
+		for i, ref := range to {
+			branch, indent := "├── ", "│   "
+			if i == len(to)-1 {
+				branch, indent = "└── ", "    "
+			}
+			if containsString(path, ref) {
+				fmt.Fprintf(w, "%s%s%s (cycle)\n", prefix, branch, ref)
+				continue
+			}
+			fmt.Fprintf(w, "%s%s%s -> %s\n", prefix, branch, ref, aliases[ref].target())
+			printTree(ref, prefix+indent, append(path, ref))
+		}
+	}
+
+	for _, name := range groups[""] {
+		fmt.Fprintf(w, "%s -> %s\n", name, aliases[name].target())
+		printTree(name, "", []string{name})
+	}
+	for _, ns := range namespaces(groups) {
+		fmt.Fprintln(w, ns)
+		names := groups[ns]
+		for i, name := range names {
+			branch, indent := "├── ", "│   "
+			if i == len(names)-1 {
+				branch, indent = "└── ", "    "
+			}
+			fmt.Fprintf(w, "%s%s -> %s\n", branch, name, aliases[name].target())
+			printTree(name, indent, []string{name})
+		}
+	}
+}
+
+// printAliasDot writes the graph of aliases to w in the DOT language of
+// Graphviz, with an edge from each alias to the aliases it refers to, and the
+// aliases of each namespace in a cluster.
+func printAliasDot(w io.Writer, aliases map[string]aliasEntry) {
+	refs := aliasRefs(aliases)
+	byNamespace := make(map[string][]string)
+	for _, name := range sortedNames(aliases) {
+		ns := namespace(name)
+		byNamespace[ns] = append(byNamespace[ns], name)
+	}
+
+	fmt.Fprintln(w, "digraph aliases {")
+	fmt.Fprintln(w, "\tnode [shape=box];")
+	node := func(indent, name string) {
+		label := name + "\n" + aliases[name].target()
+		fmt.Fprintf(w, "%s%s [label=%s];\n", indent, strconv.Quote(name), strconv.Quote(label))
+	}
+	for _, name := range byNamespace[""] {
+		node("\t", name)
+	}
+	for i, ns := range namespaces(byNamespace) {
+		fmt.Fprintf(w, "\tsubgraph cluster_%d {\n\t\tlabel=%s;\n", i, strconv.Quote(ns))
+		for _, name := range byNamespace[ns] {
+			node("\t\t", name)
+		}
+		fmt.Fprintln(w, "\t}")
+	}
+	for _, name := range sortedNames(aliases) {
+		for _, ref := range refs[name] {
+			fmt.Fprintf(w, "\t%s -> %s;\n", strconv.Quote(name), strconv.Quote(ref))
+		}
+	}
+	fmt.Fprintln(w, "}")
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// runGoWithHints runs the go command with args like runGoCapture. If the
+// command fails with output that points at the module proxy, the checksum
+// database or the network, a troubleshooting note is printed after go's own
+// output.
+func runGoWithHints(args ...string) error {
+	out, err := runGoCapture(args...)
+	if err != nil {
+		if hint := networkHint(out.Stderr.String()); hint != "" {
+			fmt.Fprint(os.Stderr, hint)
+		}
+	}
+	return err
+}
+
+// networkHint returns a troubleshooting note for msg, the output of a failed
+// go command, or "" if msg doesn't look like a proxy, checksum database or
+// network failure.
+func networkHint(msg string) string {
+	var causes []string
+	if containsAny(msg, "verifying module", "checksum mismatch", "sum.golang.org", "SECURITY ERROR", "GOSUMDB") {
+		causes = append(causes, "the checksum database rejected or could not verify a module;\n      "+
+			"for private modules, list them in GOPRIVATE or GONOSUMDB, or set GOSUMDB=off")
+	}
+	if containsAny(msg, "proxy.golang.org", "GOPROXY", "403 Forbidden", "410 Gone", "reading https://") {
+		causes = append(causes, "the module proxy failed to serve a module;\n      "+
+			"try another proxy, or GOPROXY=direct to fetch from the origin")
+	}
+	if isNetworkError(msg) {
+		causes = append(causes, "the network could not be reached;\n      "+
+			"check your connection, or use --offline to build from the module cache")
+	}
+	if len(causes) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\nhint: ")
+	b.WriteString(strings.Join(causes, "\nhint: "))
+	b.WriteString("\n")
+	if env := goEnv("GOPROXY", "GOSUMDB", "GONOSUMDB", "GOPRIVATE"); env != "" {
+		b.WriteString("hint: current settings:\n")
+		b.WriteString(env)
+	}
+	return b.String()
+}
+
+// goEnv returns the values of the go environment variables names, one
+// name=value line each, or "" if they can't be read.
+func goEnv(names ...string) string {
+	out, err := exec.Command(goBin(), append([]string{"env"}, names...)...).Output()
+	if err != nil {
+		return ""
+	}
+	var b strings.Builder
+	for i, value := range strings.Split(strings.TrimSuffix(string(out), "\n"), "\n") {
+		if i < len(names) {
+			fmt.Fprintf(&b, "\t%s=%s\n", names[i], value)
+		}
+	}
+	return b.String()
+}
+
+// containsAny reports whether s contains any of substrs.
+func containsAny(s string, substrs ...string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
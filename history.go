@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	historyFile = "history.jsonl"
+	// maxHistory is the number of entries kept in the history file.
+	maxHistory = 1000
+)
+
+// historyEntry records a single change to an alias.
+type historyEntry struct {
+	Time     time.Time `json:"time"`
+	Action   string    `json:"action"`
+	Alias    string    `json:"alias"`
+	Package  string    `json:"package,omitempty"`
+	Previous string    `json:"previous,omitempty"`
+	// Description is the new description of the alias, for historyDescribe
+	// entries, which record the old one in Previous.
+	Description string `json:"description,omitempty"`
+}
+
+// History actions.
+const (
+	historyAdd      = "add"
+	historyRetarget = "retarget"
+	historyRemove   = "remove"
+	// historyRename entries record the old name of the alias in Previous.
+	historyRename   = "rename"
+	historyDescribe = "describe"
+)
+
+// recordHistory appends entry to the history file, dropping the oldest
+// entries beyond maxHistory. History is informational, so callers should
+// report but not fail on errors.
+func recordHistory(entry historyEntry) error {
+	entry.Time = time.Now().UTC().Truncate(time.Second)
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encode history entry: %w", err)
+	}
+
+	path := profilePath(historyFile)
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read history file: %w", err)
+	}
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	if n := len(lines); n > 0 && len(lines[n-1]) == 0 {
+		lines = lines[:n-1]
+	}
+	lines = append(lines, append(line, '\n'))
+	if len(lines) > maxHistory {
+		lines = lines[len(lines)-maxHistory:]
+	}
+
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return fmt.Errorf("create config dir: %w", err)
+	}
+	if err := writeFileAtomic(path, bytes.Join(lines, nil)); err != nil {
+		return fmt.Errorf("write history file: %w", err)
+	}
+	return nil
+}
+
+// loadHistory returns the recorded history, oldest first. If alias is not
+// empty, only entries for that alias are returned.
+func loadHistory(alias string) ([]historyEntry, error) {
+	f, err := os.Open(profilePath(historyFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open history file: %w", err)
+	}
+	defer f.Close()
+
+	var entries []historyEntry
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var entry historyEntry
+		if err := json.Unmarshal(sc.Bytes(), &entry); err != nil {
+			// Skip lines that can't be decoded rather than losing the
+			// whole history.
+			continue
+		}
+		if alias == "" || entry.Alias == alias {
+			entries = append(entries, entry)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read history file: %w", err)
+	}
+	return entries, nil
+}
+
+// warnHistory records entry in the history, printing a warning if that fails.
+func warnHistory(entry historyEntry) {
+	if err := recordHistory(entry); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+)
+
+const hooksDir = "hooks"
+
+// hookPath returns the path of the hook for stage, "pre" or "post", of
+// command, or "" if there is no such hook.
+func hookPath(stage, command string) string {
+	path := filepath.Join(configDir, hooksDir, stage+"-"+command)
+	for _, p := range []string{path, path + exeSuffix()} {
+		if info, err := os.Stat(p); err == nil && !info.IsDir() {
+			return p
+		}
+	}
+	return ""
+}
+
+// runHook runs the hook for stage of command, if there is one, with args, the
+// arguments of the go command after expansion. The environment of the hook
+// holds AGO_COMMAND, the name of the command, and for post hooks
+// AGO_EXIT_STATUS, the exit status of the go command.
+func runHook(stage, command string, args []string, status int) error {
+	path := hookPath(stage, command)
+	if path == "" {
+		return nil
+	}
+	cmd := exec.Command(path, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin
+	cmd.Env = append(os.Environ(), "AGO_COMMAND="+command)
+	if stage == "post" {
+		cmd.Env = append(cmd.Env, "AGO_EXIT_STATUS="+strconv.Itoa(status))
+	}
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s-%s hook: %w", stage, command, err)
+	}
+	return nil
+}
+
+// exitStatus returns the exit status of a command that returned err.
+func exitStatus(err error) int {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	if err != nil {
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const installedFile = "installed.json"
+
+// installRecord records a package installed by ago install.
+type installRecord struct {
+	Alias   string    `json:"alias,omitempty"`
+	Package string    `json:"package"`
+	Version string    `json:"version,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// newInstallRecord returns the record of installing the package res resolved
+// to.
+func newInstallRecord(res resolution) installRecord {
+	rec := installRecord{Package: res.Result, Time: time.Now().UTC().Truncate(time.Second)}
+	if idx := strings.LastIndex(rec.Package, "@"); idx != -1 {
+		rec.Version = rec.Package[idx+1:]
+		rec.Package = rec.Package[:idx]
+	}
+	if res.Matched {
+		rec.Alias = res.Alias
+	}
+	return rec
+}
+
+// loadInstalled returns the manifest of installed packages.
+func loadInstalled() ([]installRecord, error) {
+	data, err := os.ReadFile(profilePath(installedFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read installed manifest: %w", err)
+	}
+	var records []installRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("decode installed manifest: %w", err)
+	}
+	return records, nil
+}
+
+// recordInstalls adds records to the manifest of installed packages,
+// replacing any earlier records of the same packages.
+func recordInstalls(records []installRecord) error {
+	existing, err := loadInstalled()
+	if err != nil {
+		return err
+	}
+	byPackage := make(map[string]installRecord, len(existing)+len(records))
+	for _, rec := range append(existing, records...) {
+		byPackage[rec.Package] = rec
+	}
+	merged := make([]installRecord, 0, len(byPackage))
+	for _, rec := range byPackage {
+		merged = append(merged, rec)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Package < merged[j].Package
+	})
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(merged); err != nil {
+		return fmt.Errorf("encode installed manifest: %w", err)
+	}
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return fmt.Errorf("create config dir: %w", err)
+	}
+	if err := writeFileAtomic(profilePath(installedFile), buf.Bytes()); err != nil {
+		return fmt.Errorf("write installed manifest: %w", err)
+	}
+	return nil
+}
+
+// printInstalled prints the manifest of installed packages.
+func printInstalled(records []installRecord) error {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PACKAGE\tVERSION\tALIAS\tINSTALLED")
+	fmt.Fprintln(tw, "-------\t-------\t-----\t---------")
+	for _, rec := range records {
+		version, alias := rec.Version, rec.Alias
+		if version == "" {
+			version = "-"
+		}
+		if alias == "" {
+			alias = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", rec.Package, version, alias, rec.Time.Local().Format("2006-01-02 15:04:05"))
+	}
+	return tw.Flush()
+}
+
+// syncInstalled reinstalls every package in the manifest. Each package is
+// installed by its own go install command, since go install only accepts
+// packages from a single module when given versions.
+func syncInstalled(records []installRecord) error {
+	var failed []string
+	for _, rec := range records {
+		version := rec.Version
+		if version == "" {
+			version = "latest"
+		}
+		if err := runGo("install", rec.Package+"@"+version); err != nil {
+			failed = append(failed, rec.Package)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to install %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
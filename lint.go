@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// lintAliases checks aliases for common problems: surrounding whitespace,
+// empty names or packages, and repeated slashes in packages. It returns a
+// cleaned copy of aliases and a description of each problem found.
+func lintAliases(aliases map[string]aliasEntry) (map[string]aliasEntry, []string) {
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+	// Names that are already clean come first, so that when trimming a name
+	// collides with another alias, the alias that was clean is kept.
+	sort.Slice(names, func(i, j int) bool {
+		cleanI := strings.TrimSpace(names[i]) == names[i]
+		cleanJ := strings.TrimSpace(names[j]) == names[j]
+		if cleanI != cleanJ {
+			return cleanI
+		}
+		return names[i] < names[j]
+	})
+
+	var problems []string
+	cleaned := make(map[string]aliasEntry, len(aliases))
+	for _, name := range names {
+		entry := aliases[name]
+
+		fixedName := strings.TrimSpace(name)
+		if fixedName == "" {
+			problems = append(problems, fmt.Sprintf("alias %q: empty name, removed", name))
+			continue
+		}
+		if fixedName != name {
+			problems = append(problems, fmt.Sprintf("alias %q: trimmed whitespace from name", name))
+		}
+
+		if entry.isBundle() {
+			bundle := make([]string, len(entry.Bundle))
+			for i, pkg := range entry.Bundle {
+				bundle[i] = collapseSlashes(strings.TrimSpace(pkg))
+				if bundle[i] != pkg {
+					problems = append(problems, fmt.Sprintf("alias %q: cleaned bundle package %q", name, pkg))
+				}
+			}
+			entry.Bundle = bundle
+		} else {
+			pkg := strings.TrimSpace(entry.Package)
+			if pkg == "" {
+				problems = append(problems, fmt.Sprintf("alias %q: empty package, removed", name))
+				continue
+			}
+			if pkg != entry.Package {
+				problems = append(problems, fmt.Sprintf("alias %q: trimmed whitespace from package", name))
+			}
+			if fixed := collapseSlashes(pkg); fixed != pkg {
+				problems = append(problems, fmt.Sprintf("alias %q: collapsed repeated slashes in package %q", name, pkg))
+				pkg = fixed
+			}
+			entry.Package = pkg
+		}
+
+		if _, ok := cleaned[fixedName]; ok {
+			problems = append(problems, fmt.Sprintf("alias %q: duplicates alias %q once trimmed, removed", name, fixedName))
+			continue
+		}
+		cleaned[fixedName] = entry
+	}
+	return cleaned, problems
+}
+
+// collapseSlashes replaces runs of slashes in the package path pkg with a
+// single slash. Values with a URL scheme and directory paths, which may be
+// UNC paths such as //server/share, are left alone.
+func collapseSlashes(pkg string) string {
+	if strings.Contains(pkg, "://") || isPathAlias(pkg) {
+		return pkg
+	}
+	for strings.Contains(pkg, "//") {
+		pkg = strings.ReplaceAll(pkg, "//", "/")
+	}
+	return pkg
+}
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// listOptions controls the output of printAliasList.
+type listOptions struct {
+	// JSON prints the aliases as JSON, in the same form as the aliases
+	// file, rather than as a table.
+	JSON bool
+	// NoTruncate disables the truncation of packages that would overflow
+	// the width of the terminal.
+	NoTruncate bool
+	// Count prints just the number of aliases.
+	Count bool
+	// Columns are the columns of the table, in order. If empty,
+	// defaultColumns are used.
+	Columns []string
+	// Template, if set, is executed for each alias instead of printing a
+	// table.
+	Template *template.Template
+	// Long adds the example column to the table.
+	Long bool
+	// Resolve holds the aliases the example column is resolved with, which
+	// may be more than those listed.
+	Resolve map[string]aliasEntry
+	// Compact prints name=package pairs instead of a table: as many to a
+	// line as fit the terminal, or one per line when not printing to a
+	// terminal.
+	Compact bool
+	// Packages prints just the packages of the aliases, one per line.
+	Packages bool
+}
+
+// templateAlias is the data a list template is executed with.
+type templateAlias struct {
+	Alias       string
+	Package     string
+	Bundle      []string
+	Version     string
+	MinGo       string
+	RawMajor    bool
+	ExactMatch  bool
+	Description string
+	Tags        []string
+}
+
+// parseListTemplate parses text, a template for a line of the alias list. The
+// template may use strings.Join as join, to format tags.
+func parseListTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("list").Funcs(template.FuncMap{"join": strings.Join}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// listColumns maps the name of each column printAliasList can show to the
+// value of the column for an alias.
+var listColumns = map[string]func(name string, e aliasEntry) string{
+	"alias":     func(name string, e aliasEntry) string { return name },
+	"package":   func(name string, e aliasEntry) string { return e.target() },
+	"desc":      func(name string, e aliasEntry) string { return e.Description },
+	"tags":      func(name string, e aliasEntry) string { return strings.Join(e.Tags, ",") },
+	"version":   func(name string, e aliasEntry) string { return e.Version },
+	"min-go":    func(name string, e aliasEntry) string { return e.MinGo },
+	"raw-major": func(name string, e aliasEntry) string { return strconv.FormatBool(e.RawMajor) },
+	"exact":     func(name string, e aliasEntry) string { return strconv.FormatBool(e.ExactMatch) },
+}
+
+var defaultColumns = []string{"alias", "package"}
+
+// exampleColumn shows what ago get would expand an alias to. It is computed
+// by printAliasList itself, since chained aliases need every alias.
+const exampleColumn = "example"
+
+// example returns an example of using the alias name with ago get, and what it
+// expands to. Namespace aliases, which end in a slash, are shown with a
+// package within them.
+func example(aliases map[string]aliasEntry, name string) string {
+	arg := name
+	if strings.HasSuffix(name, "/") {
+		arg += "X"
+	}
+	res, err := resolve(aliases, arg)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	applyPinnedVersion(aliases, &res)
+	return arg + " -> " + res.Result
+}
+
+// parseColumns parses a comma-separated list of column names.
+func parseColumns(s string) ([]string, error) {
+	columns := strings.Split(s, ",")
+	for i, c := range columns {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if _, ok := listColumns[c]; !ok && c != exampleColumn {
+			names := []string{exampleColumn}
+			for name := range listColumns {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("unknown column %q; valid columns are %s", c, strings.Join(names, ", "))
+		}
+		columns[i] = c
+	}
+	return columns, nil
+}
+
+// filterAliases returns the aliases whose names begin with prefix and match
+// the glob pattern, as understood by path.Match. An empty prefix or pattern
+// matches every alias.
+func filterAliases(aliases map[string]aliasEntry, prefix, pattern string) (map[string]aliasEntry, error) {
+	if pattern != "" {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	filtered := make(map[string]aliasEntry)
+	for name, entry := range aliases {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if pattern != "" {
+			if ok, _ := path.Match(pattern, name); !ok {
+				continue
+			}
+		}
+		filtered[name] = entry
+	}
+	return filtered, nil
+}
+
+// aliasesByTarget returns the aliases whose package is pkg or, with prefix,
+// also any package within it. Bundles never match, since removing one would
+// lose its other packages.
+func aliasesByTarget(aliases map[string]aliasEntry, pkg string, prefix bool) map[string]aliasEntry {
+	pkg = strings.TrimSuffix(pkg, "/")
+	matched := make(map[string]aliasEntry)
+	for name, entry := range aliases {
+		if entry.isBundle() {
+			continue
+		}
+		if entry.Package == pkg || (prefix && strings.HasPrefix(entry.Package, pkg+"/")) {
+			matched[name] = entry
+		}
+	}
+	return matched
+}
+
+// printAliasList prints aliases to standard output, sorted by name.
+func printAliasList(aliases map[string]aliasEntry, opts listOptions) error {
+	if opts.Count {
+		fmt.Println(len(aliases))
+		return nil
+	}
+	if opts.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(aliases)
+	}
+
+	if opts.Template != nil {
+		for _, name := range sortedNames(aliases) {
+			e := aliases[name]
+			var buf bytes.Buffer
+			err := opts.Template.Execute(&buf, templateAlias{
+				Alias:       name,
+				Package:     e.Package,
+				Bundle:      e.Bundle,
+				Version:     e.Version,
+				MinGo:       e.MinGo,
+				RawMajor:    e.RawMajor,
+				ExactMatch:  e.ExactMatch,
+				Description: e.Description,
+				Tags:        e.Tags,
+			})
+			if err != nil {
+				return fmt.Errorf("execute template for alias %q: %w", name, err)
+			}
+			if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+				buf.WriteByte('\n')
+			}
+			os.Stdout.Write(buf.Bytes())
+		}
+		return nil
+	}
+
+	if opts.Compact {
+		return printCompact(aliases)
+	}
+	if opts.Packages {
+		for _, pkg := range aliasPackages(aliases) {
+			fmt.Println(pkg)
+		}
+		return nil
+	}
+
+	columns := opts.Columns
+	if len(columns) == 0 {
+		columns = defaultColumns
+	}
+	if opts.Long {
+		columns = append(columns[:len(columns):len(columns)], exampleColumn)
+	}
+	resolveWith := opts.Resolve
+	if resolveWith == nil {
+		resolveWith = aliases
+	}
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Build the table first, so that the width left for packages is known.
+	rows := make([][]string, len(names))
+	widths := make([]int, len(columns))
+	for i, c := range columns {
+		widths[i] = len(c)
+	}
+	for r, name := range names {
+		rows[r] = make([]string, len(columns))
+		for i, c := range columns {
+			var v string
+			if c == exampleColumn {
+				v = example(resolveWith, name)
+			} else {
+				v = listColumns[c](name, aliases[name])
+			}
+			if v == "" {
+				v = "-"
+			}
+			rows[r][i] = v
+			widths[i] = maxInt(widths[i], utf8.RuneCountInString(v))
+		}
+	}
+
+	// Long packages are truncated to fit the terminal, but only when the
+	// output is a terminal, so that the full values can always be piped.
+	pkgWidth := -1
+	if !opts.NoTruncate {
+		if width, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+			pkgWidth = width
+			for i, c := range columns {
+				if c != "package" {
+					pkgWidth -= widths[i] + 2
+				}
+			}
+		}
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := make([]string, len(columns))
+	rule := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = strings.ToUpper(c)
+		rule[i] = strings.Repeat("-", len(c))
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	fmt.Fprintln(tw, strings.Join(rule, "\t"))
+	for _, row := range rows {
+		for i, c := range columns {
+			if c == "package" {
+				row[i] = truncate(row[i], pkgWidth)
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// aliasPackages returns the packages of aliases, including each package of a
+// bundle, sorted and without repeats. Only packages go commands accept are
+// returned, so directories, schemes and namespace aliases are left out.
+func aliasPackages(aliases map[string]aliasEntry) []string {
+	seen := make(map[string]bool)
+	var pkgs []string
+	for name, e := range aliases {
+		if strings.HasSuffix(name, "/") {
+			continue
+		}
+		targets := e.Bundle
+		if !e.isBundle() {
+			targets = []string{e.Package}
+		}
+		for _, pkg := range targets {
+			if isPathAlias(pkg) || hasScheme(pkg) || seen[pkg] {
+				continue
+			}
+			seen[pkg] = true
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	sort.Strings(pkgs)
+	return pkgs
+}
+
+// printCompact prints aliases as name=package pairs sorted by name, the
+// packages of bundles separated by commas.
+func printCompact(aliases map[string]aliasEntry) error {
+	width := -1
+	if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+		width = w
+	}
+	var b strings.Builder
+	var lineLen int
+	for _, name := range sortedNames(aliases) {
+		e := aliases[name]
+		pkg := e.Package
+		if e.isBundle() {
+			pkg = strings.Join(e.Bundle, ",")
+		}
+		pair := name + "=" + pkg
+		n := utf8.RuneCountInString(pair)
+		switch {
+		case lineLen == 0:
+		case width >= 0 && lineLen+1+n <= width:
+			b.WriteByte(' ')
+			lineLen++
+		default:
+			b.WriteByte('\n')
+			lineLen = 0
+		}
+		b.WriteString(pair)
+		lineLen += n
+	}
+	if b.Len() > 0 {
+		b.WriteByte('\n')
+	}
+	_, err := os.Stdout.WriteString(b.String())
+	return err
+}
+
+// minTruncateWidth is the narrowest a value is ever truncated to, below which
+// it would be unrecognizable.
+const minTruncateWidth = 16
+
+// truncate shortens s to width runes, ending with an ellipsis. A negative
+// width leaves s untouched.
+func truncate(s string, width int) string {
+	if width < 0 || utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	width = maxInt(width, minTruncateWidth)
+	r := []rune(s)
+	if len(r) <= width {
+		return s
+	}
+	return string(r[:width-1]) + "…"
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// printAlias prints the definition of a single alias.
+func printAlias(name string, entry aliasEntry) error {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "alias\t%s\n", name)
+	if entry.isBundle() {
+		fmt.Fprintf(tw, "bundle\t%s\n", strings.Join(entry.Bundle, ", "))
+	} else {
+		fmt.Fprintf(tw, "package\t%s\n", entry.Package)
+	}
+	if entry.Version != "" {
+		fmt.Fprintf(tw, "version\t%s\n", entry.Version)
+	}
+	if entry.MinGo != "" {
+		fmt.Fprintf(tw, "min go\t%s\n", entry.MinGo)
+	}
+	if entry.RawMajor {
+		fmt.Fprintln(tw, "raw major\ttrue")
+	}
+	if entry.ExactMatch {
+		fmt.Fprintln(tw, "exact match\ttrue")
+	}
+	if entry.Description != "" {
+		fmt.Fprintf(tw, "description\t%s\n", entry.Description)
+	}
+	if len(entry.Tags) > 0 {
+		fmt.Fprintf(tw, "tags\t%s\n", strings.Join(entry.Tags, ", "))
+	}
+	return tw.Flush()
+}
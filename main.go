@@ -1,23 +1,32 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"sort"
-	"strconv"
 	"strings"
 	"text/tabwriter"
+	"time"
+
+	"golang.org/x/mod/semver"
+	"golang.org/x/term"
 )
 
 const agoUsage = `usage: ago <command> [arguments]
 
 ago is a wrapper around the go command that adds the ability to alias packages
-with short, memorable names. Only the get and install commands are affected. All
-other flags and arguments are passed through to the go command.
+with short, memorable names. Only the get, install, build, test, vet, generate
+and doc commands are affected. All other flags and arguments are passed through
+to the go command.
 
 create aliases with the alias command:
 
@@ -34,10 +43,46 @@ which is equivalent to:
 The commands are:
 
 	alias, a      create/manage package aliases
+	init          create an aliases file with example aliases
 	get           download packages and dependencies
 	install       compile and install packages and dependencies
+	build         compile packages and dependencies
+	test          test packages
+	vet           report likely mistakes in packages
+	generate      generate Go files by processing source
+	doc           show documentation for package or symbol
+	info          show module information for an alias
+	x, run-alias  run an aliased tool, installing it if needed
+	installed     list or reinstall recorded installs
+	which, expand print what each argument expands to, or --json for details
+	explain       describe how a command's arguments would be expanded
+	check         fail unless every argument of a command matches an alias
+	profile       list alias profiles
+	env           print ago's settings, or go's for other keys
+	tidy          get the given packages, if any, then run go mod tidy
+	which-go      print the go command used and its version
+	version       print the ago version
+	self-update   upgrade ago to the latest version
 	help          display this help text
 
+Other commands are passed to the go command untouched, unless the --expand
+flag is given before the command:
+
+	$ ago --expand list foo/...
+
+Give --trace before the command, or set AGO_TRACE=1, to print every step of
+expanding the arguments to standard error, on lines beginning with ago-trace:.
+
+Give --no-expand before the command, or set AGO_NO_EXPAND=1, to run go
+without expanding any aliases:
+
+	$ ago --no-expand get foo
+
+ago work use expands aliases of directories only, such as ~/src/mylib.
+
+ago exits with the go command's status when it runs go, and with a status
+between 64 and 78 when ago itself fails; see the README for their meanings.
+
 `
 
 const aliasUsage = `usage:
@@ -46,223 +91,2061 @@ create an alias:
 
 	ago alias foo github.com/foo/bar/v2
 
-remove an alias:
+create an alias named after a subcommand, such as lint:
+
+	ago alias add lint github.com/foo/lint
+
+create a bundle alias that stands for several packages:
+
+	ago alias devtools github.com/a/x github.com/b/y
+
+create an alias with a description and tags:
+
+	ago alias foo github.com/foo/bar/v2 --desc "the bar library" --tags web,http
+
+create an alias that passes major versions through verbatim:
+
+	ago alias foo github.com/foo/bar --raw-major
+
+create an alias that only matches its exact name, not longer paths:
+
+	ago alias foo github.com/foo/bar --exact
+
+create an alias for the package most recently fetched with ago get or install:
+
+	ago alias foo --last
+
+create an alias with a pinned version, used by get and install when none is
+given:
+
+	ago alias foo github.com/foo/bar/v2 --version v2.3.0
+
+create an alias whose package requires a minimum version of Go to install:
+
+	ago alias foo github.com/foo/bar/v2 --min-go 1.21
+
+rename an alias, or every alias matching a regular expression, replacing the
+matches as with Go's regexp.ReplaceAllString. Aliases chained to a renamed
+alias are updated to its new name:
+
+	ago alias rename foo bar
+	ago alias rename --regex '_' '-' [--dry-run]
+
+remove aliases by name, or every alias with a prefix or matching a glob
+pattern, after confirming unless --yes is given:
+
+	ago alias rm foo bar [--yes]
+	ago alias rm --prefix work/ [--dry-run] [--yes]
+	ago alias rm --glob 'work/*'
+
+remove aliases that have never been used, or not within a duration:
+
+	ago alias rm --unused [--since 30d]
+
+list all aliases, with long packages truncated to fit the terminal unless
+--no-truncate is given, or as JSON:
+
+	ago alias list [--no-truncate] [--json]
+
+list only the aliases with a prefix, or matching a glob pattern, or just count
+them:
 
-	ago alias rm foo
+	ago alias list --prefix work/
+	ago alias list 'work/*'
+	ago alias list --prefix work/ --count
 
-list all aliases:
+list only the aliases added or changed within a duration:
 
-	ago alias list
+	ago alias list --since 7d
+
+add an example of what ago get would expand each alias to:
+
+	ago alias list --long
+
+print name=package pairs instead of a table, as many to a line as fit:
+
+	ago alias list --compact
+
+print just the aliased packages, one per line without repeats:
+
+	ago alias list --packages
+	ago alias packages | xargs go get
+
+choose the columns of the list, and their order, from alias, package, desc,
+tags, version, min-go, raw-major, exact and example:
+
+	ago alias list --columns alias,package,desc,tags
+
+format each alias with a Go template instead; the fields are .Alias, .Package,
+.Bundle, .Version, .MinGo, .RawMajor, .ExactMatch, .Description and .Tags:
+
+	ago alias list --template '{{.Alias}} => {{.Package}}'
+
+show the definition of an alias:
+
+	ago alias show foo
+
+back up aliases, list backups, and restore a backup:
+
+	ago alias backup [name]
+	ago alias backup list
+	ago alias restore [--yes] <name>
+
+report which aliases with a pinned version have a newer version available:
+
+	ago alias check-updates
+
+move an alias to the latest major version of its module, or to a given one:
+
+	ago alias bump [--yes] foo
+	ago alias bump --to v3 foo
+
+import aliases from a file, or from standard input with - or --stdin:
+
+	ago alias import aliases.json
+	ago alias export | ssh host ago alias import --stdin
+
+after importing, report aliases sharing a package, and keep only the shortest
+named or first of each, where first prefers aliases that existed before:
+
+	ago alias import --dedupe [--keep shortest|first|all] aliases.json
+
+rename the imported aliases before merging, adding a prefix to each name or
+replacing matches of a regular expression:
+
+	ago alias import --transform prefix:team/ theirs.json
+
+mirror a file, removing aliases missing from it after confirming unless --yes
+is given, or only report what would change with --dry-run:
+
+	ago alias import --prune-missing [--dry-run] [--yes] team.json
+	ago alias import --transform 'regexp:^old-=new-' theirs.json
+
+compare the aliases with those in a file before importing it, as a table or as
+JSON:
+
+	ago alias diff theirs.json [--json]
 
 The sub-commands are:
 
+	add               create an alias, even one named after a sub-command
 	list, ls, l       list all aliases
-	rm                remove an alias
+	count             print the number of aliases
+	packages          print the aliased packages, one per line
+	show              show the definition of an alias
+	desc              set or, given "", clear the description of an alias
+	open              open the repository of an alias in a browser, or --print it
+	rm                remove aliases
+	rm-target         remove the aliases of a package, or --prefix of a module
+	rename, mv        rename aliases, by name or by regular expression
+	swap              exchange the packages and settings of two aliases
+	import            merge aliases from a file or standard input
+	export            write all aliases to standard output
+	resolve-all       list the aliases in effect, with the source of each
+	diff              compare the aliases with those in a file
+	sort              rewrite the aliases file in canonical form
+	normalize-major   canonicalize major version suffixes of packages
+	history           show recent changes to aliases
+	deps              show which of a go.mod's modules have aliases
+	graph             show which aliases refer to others, or --dot for Graphviz
+	lint              report problems in the aliases file, or fix them with --fix
+	backup            back up the aliases file, or list backups
+	restore           replace the aliases file with a backup
+	check-updates     report newer versions of pinned aliases
+	bump, touch       move an alias to the latest or given major version
+	completion-data   print alias names for shell completion scripts
 	help	          display this help text
 
 `
 
+const profileUsage = `usage:
+
+list all profiles:
+
+	ago profile list
+
+the active profile is selected with the AGO_PROFILE environment variable or the
+--profile flag:
+
+	ago --profile work get foo
+
+The sub-commands are:
+
+	list, ls, l       list all profiles
+	help              display this help text
+
+`
+
+const installedUsage = `usage:
+
+record installed packages with --record, or by setting recordInstalls in
+config.json:
+
+	ago install --record foo@latest
+
+list recorded installs:
+
+	ago installed list
+
+reinstall every recorded package:
+
+	ago installed sync
+
+The sub-commands are:
+
+	list, ls, l       list recorded installs
+	sync              reinstall every recorded package
+	help              display this help text
+
+`
+
+// parseGlobalFlags removes the flags preceding the command from args and
+// applies them. The returned slice begins with the program name, followed by
+// the command and its arguments.
+func parseGlobalFlags(args []string) ([]string, error) {
+	rest := []string{args[0]}
+	i := 1
+	for ; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "--") {
+			break
+		}
+		name, value, hasValue := strings.Cut(arg[2:], "=")
+		switch name {
+		case "profile":
+			if !hasValue {
+				if i+1 >= len(args) {
+					return nil, fmt.Errorf("flag --%s requires a value", name)
+				}
+				i++
+				value = args[i]
+			}
+			profile = value
+		case "module-root":
+			if !hasValue {
+				if i+1 >= len(args) {
+					return nil, fmt.Errorf("flag --%s requires a value", name)
+				}
+				i++
+				value = args[i]
+			}
+			moduleRootFlag = value
+		case "offline":
+			offline = true
+		case "expand":
+			forceExpand = true
+		case "no-expand":
+			noExpand = true
+		case "trace":
+			traceEnabled = true
+		default:
+			return nil, fmt.Errorf("unknown flag %q", arg)
+		}
+	}
+	return append(rest, args[i:]...), nil
+}
+
+// agoCommands are the commands ago carries out itself, rather than by running
+// go with the same command.
+var agoCommands = map[string]bool{
+	"help": true, "x": true, "run-alias": true, "installed": true, "which-go": true,
+	"version": true, "self-update": true, "explain": true, "which": true, "expand": true,
+	"init": true, "check": true, "info": true, "profile": true, "alias": true, "a": true,
+	"env": true, "tidy": true,
+}
+
+// checkAliasArgs exits with a usage error if the alias subcommand in args is
+// given more than max arguments. Since ago alias <name> <package> creates an
+// alias, extra arguments most likely mean that an alias named after the
+// subcommand was meant, which ago alias add creates.
+func checkAliasArgs(args []string, max int) {
+	if len(args) <= 3+max {
+		return
+	}
+	fatalf(exitUsage, "error: unexpected argument %q to alias %s; to create an alias named %q, use ago alias add %s",
+		args[3+max], args[2], args[2], strings.Join(args[2:], " "))
+}
+
+// cutFlag removes every occurrence of the boolean flag --name from the
+// arguments following the command in args, and reports whether it was found.
+func cutFlag(args []string, name string) ([]string, bool) {
+	var found bool
+	rest := args[:2:2]
+	for _, arg := range args[2:] {
+		if arg == "--"+name {
+			found = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return rest, found
+}
+
+// cutFlagValue removes every occurrence of the flag --name and its value from
+// the arguments following the command in args, and returns the last value.
+// The value may be given as the next argument or after an equals sign.
+func cutFlagValue(args []string, name string) ([]string, string, error) {
+	rest, values, err := cutFlagValues(args, name)
+	if err != nil || len(values) == 0 {
+		return rest, "", err
+	}
+	return rest, values[len(values)-1], nil
+}
+
+// cutFlagValues is like cutFlagValue, but returns the values of every
+// occurrence of the flag, for flags that may be repeated.
+func cutFlagValues(args []string, name string) ([]string, []string, error) {
+	var values []string
+	rest := args[:2:2]
+	for i := 2; i < len(args); i++ {
+		arg := args[i]
+		if v, ok := strings.CutPrefix(arg, "--"+name+"="); ok {
+			values = append(values, v)
+			continue
+		}
+		if arg == "--"+name {
+			if i+1 >= len(args) {
+				return nil, nil, fmt.Errorf("flag --%s requires a value", name)
+			}
+			i++
+			values = append(values, args[i])
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return rest, values, nil
+}
+
+// requireNetwork returns an error if network access has been disabled with
+// --offline. feature names the command that needs the network.
+func requireNetwork(feature string) error {
+	if offline {
+		return fmt.Errorf("%s requires network access, which is disabled by --offline", feature)
+	}
+	return nil
+}
+
 func main() {
-	if len(os.Args) < 2 {
+	args, err := parseGlobalFlags(os.Args)
+	if err != nil {
+		fatalf(exitUsage, "error: %v", err)
+	}
+	if strings.ContainsAny(profile, `/\`) {
+		fatalf(exitUsage, "error: invalid profile name %q", profile)
+	}
+	if len(args) < 2 {
 		fmt.Print(agoUsage)
 		return
 	}
 
-	aliases, err := loadAliases()
+	project, err := loadProject()
 	if err != nil {
-		fatalf("error: %v", err)
+		fatalf(exitConfig, "error: %v", err)
 	}
+	if cfg, err = loadSettings(project); err != nil {
+		fatalf(exitConfig, "error: %v", err)
+	}
+	// With expansion disabled, go is run exactly as ago was, before the
+	// aliases are even read, so that they can be ruled out as the cause of
+	// a problem.
+	// ago tidy has no go equivalent, so it still runs go get, with its
+	// packages as given, and then go mod tidy.
+	if noExpand && args[1] == "tidy" {
+		steps := [][]string{{"mod", "tidy"}}
+		if len(args) > 2 {
+			steps = append([][]string{append([]string{"get"}, args[2:]...)}, steps...)
+		}
+		for _, step := range steps {
+			fmt.Printf("> go %s (alias expansion disabled)\n", strings.Join(step, " "))
+			if err := goCommand(step...).Run(); err != nil {
+				exitWithError(err)
+			}
+		}
+		return
+	}
+	if noExpand && !agoCommands[args[1]] {
+		fmt.Printf("> go %s (alias expansion disabled)\n", strings.Join(args[1:], " "))
+		if err := goCommand(args[1:]...).Run(); err != nil {
+			exitWithError(err)
+		}
+		return
+	}
+	// ago tidy foo is ago get --tidy foo, getting the packages before
+	// tidying. Without packages, it just runs go mod tidy.
+	if args[1] == "tidy" {
+		if len(args) == 2 {
+			args = []string{args[0], "mod", "tidy"}
+		} else {
+			args = append([]string{args[0], "get", "--tidy"}, args[2:]...)
+		}
+	}
+	aliases, err := loadAliases(aliasesPath())
+	if err != nil {
+		fatalf(exitConfig, "error: %v", err)
+	}
+	// Aliases from the environment are used when expanding arguments, but
+	// are never written to the aliases file.
+	env, err := envAliases()
+	if err != nil {
+		fatalf(exitConfig, "error: %v", err)
+	}
+	// Aliases given with --alias apply to this command only.
+	var inline map[string]aliasEntry
+	switch args[1] {
+	case "get", "install", "build", "test", "vet", "generate", "which", "expand":
+		var words []string
+		if args, words, err = cutFlagValues(args, "alias"); err != nil {
+			fatalf(exitUsage, "error: %v", err)
+		}
+		if inline, err = parseAliasWords(words); err != nil {
+			fatalf(exitUsage, "error: --alias: %v", err)
+		}
+	}
+	layers := aliasLayers{Global: aliases, Env: env, Project: project.Aliases, Inline: inline}
+	effective := layers.merged()
 
-	args := make([]string, len(os.Args))
-	copy(args, os.Args)
-
+	var (
+		ok, tidy      bool
+		hints         bool
+		recordInstall bool
+		installs      []installRecord
+		pins          map[string]string
+		repackages    map[string]string
+		used          []string
+	)
 	switch args[1] {
 	case "help":
 		fmt.Print(agoUsage)
 		return
-	case "get", "install":
-		if len(args) > 2 {
-			for i := 2; i < len(args); i++ {
-				arg := args[i]
-
-				// Find the alias with the longest matching prefix.
-				var alias string
-				var pkg string
-				for a, p := range aliases {
-					if strings.HasPrefix(arg, a) && len(a) > len(alias) {
-						alias = a
-						pkg = p
+	case "get", "install", "build", "test", "vet", "generate":
+		if args, ok = cutFlag(args, "offline"); ok {
+			offline = true
+		}
+		if args[1] == "get" || args[1] == "install" {
+			args, tidy = cutFlag(args, "tidy")
+		}
+		if args[1] == "install" {
+			var record bool
+			args, record = cutFlag(args, "record")
+			recordInstall = record || cfg.RecordInstalls
+		}
+		args, hints = cutFlag(args, "hints")
+		var exportResolved bool
+		args, exportResolved = cutFlag(args, "export-resolved")
+		var resolved []string
+		var noDedup bool
+		args, noDedup = cutFlag(args, "no-dedup")
+		// The packages of a bundle or a file are listed before fetching
+		// them, and the fetch is confirmed.
+		var multi, dryRun, yes bool
+		if args[1] == "get" || args[1] == "install" {
+			args, dryRun = cutFlag(args, "dry-run")
+			args, yes = cutFlag(args, "yes")
+			var fromFile string
+			if args, fromFile, err = cutFlagValue(args, "from-file"); err != nil {
+				fatalf(exitUsage, "error: %v", err)
+			}
+			if fromFile != "" {
+				pkgs, err := readPackageList(fromFile)
+				if err != nil {
+					fatalf(exitNoInput, "error: %v", err)
+				}
+				args = append(args, pkgs...)
+				multi = true
+			}
+		}
+		var replaceDir string
+		var updateAliases, bumpMinor, bumpMajor bool
+		if args[1] == "get" {
+			if args, replaceDir, err = cutFlagValue(args, "replace-local"); err != nil {
+				fatalf(exitUsage, "error: %v", err)
+			}
+			args, updateAliases = cutFlag(args, "update-aliases")
+			args, bumpMinor = cutFlag(args, "bump-minor")
+			args, bumpMajor = cutFlag(args, "bump-major")
+			if bumpMinor && bumpMajor {
+				fatalf(exitUsage, "error: --bump-minor and --bump-major cannot be used together")
+			}
+		}
+		var installedGo string
+		expanded, err := expandArgs(effective, args[1], args[2:], func(res *resolution) {
+			if bumpMinor || bumpMajor {
+				pkg, err := bumpResolution(effective, res, bumpMajor)
+				if err != nil {
+					fatalf(exitDataErr, "error: %v", err)
+				}
+				if pkg != "" && updateAliases {
+					if repackages == nil {
+						repackages = make(map[string]string)
 					}
+					repackages[res.Alias] = pkg
 				}
-				if alias == "" {
-					continue
+			}
+			if args[1] == "install" {
+				if err := checkMinGo(effective, res.Chain, &installedGo); err != nil {
+					fatalf(exitUnavailable, "error: %v", err)
 				}
-
-				// If the user is requesting a specific version, extract it.
-				var version string
-				if idx := strings.LastIndex(arg, "@"); idx != -1 {
-					version = arg[idx:]
-					arg = arg[:idx]
+			}
+			if !res.Matched && (args[1] == "get" || args[1] == "install") {
+				if s := suggestAlias(effective, res.Arg); s != "" {
+					fmt.Fprintf(os.Stderr, "hint: no alias matches %q; did you mean %q?\n", res.Arg, s)
 				}
-
-				pkgPath := strings.TrimPrefix(arg, alias)
-
-				// If the package path starts with a major version, then we need
-				// to strip it off and replace it with the aliased package path.
-				var major string
-				if split := strings.SplitN(pkgPath, "/", 3); len(split) > 1 {
-					if split[1][0] == 'v' {
-						if _, err := strconv.Atoi(split[1][1:]); err == nil {
-							major = "/" + split[1]
-							if len(split) > 2 {
-								pkgPath = "/" + split[2]
-							} else {
-								pkgPath = ""
+			}
+			if res.Matched {
+				resolved = append(resolved, res.Alias+"="+res.Result)
+				used = append(used, res.Chain...)
+			}
+			if updateAliases && res.Matched && res.Version != "" {
+				// A query such as latest would never change the
+				// version a bare alias gets, so only versions are
+				// pinned.
+				if !semver.IsValid(res.Version) {
+					fmt.Fprintf(os.Stderr, "note: %s is not a version, so alias %q is not updated\n", res.Version, res.Alias)
+				} else {
+					// The module is that of the last alias of
+					// the chain, which names a package.
+					entry := effective[res.Chain[len(res.Chain)-1]]
+					pkgs := entry.Bundle
+					if pkg, ok := repackages[res.Alias]; ok {
+						pkgs = []string{pkg}
+					} else if !entry.isBundle() {
+						pkgs = []string{entry.Package}
+					}
+					if !cfg.GopathMode && !entry.RawMajor {
+						for _, pkg := range pkgs {
+							if err := checkPinMajor(res.Alias, pkg, res.Version); err != nil {
+								fatalf(resolveExitCode(err), "error: %v", err)
 							}
 						}
 					}
-				}
-
-				// If the user has requested a specific major version, and the
-				// aliased package path already contains a major version, then
-				// we need to strip it off and replace it with the requested
-				// major version. Unless the requested major version < 2, in
-				// which case we just strip it off.
-				if major != "" {
-					// Strip off the major version.
-					if idx := strings.LastIndex(pkg, "/v"); idx != -1 {
-						if _, err := strconv.Atoi(pkg[idx+2:]); err == nil {
-							pkg = pkg[:idx]
-						}
+					if pins == nil {
+						pins = make(map[string]string)
 					}
-
-					// If the requested major version is < 2, then set it to
-					// the empty string.
-					if len(major) == 3 && (major[2] == '0' || major[2] == '1') {
-						major = ""
+					pins[res.Alias] = res.Version
+				}
+			}
+			if res.Bundle != nil {
+				multi = true
+				// A bundle records an install of each of its packages.
+				for _, pkg := range res.Bundle {
+					if recordInstall {
+						r := *res
+						r.Result = pkg
+						installs = append(installs, newInstallRecord(r))
 					}
 				}
-
-				args[i] = pkg + major + pkgPath + version
+			} else if recordInstall {
+				installs = append(installs, newInstallRecord(*res))
 			}
+		})
+		if err != nil {
+			fatalf(resolveExitCode(err), "error: %v", err)
 		}
-	case "alias", "a":
-		if len(args) < 3 {
-			fmt.Print(aliasUsage)
+		args = append(args[:2:2], expanded...)
+		if exportResolved {
+			goEnvExtra = append(goEnvExtra, "AGO_RESOLVED="+strings.Join(resolved, ";"))
+		}
+		if (args[1] == "get" || args[1] == "install") && !noDedup {
+			var dups []string
+			args, dups = dedupArgs(args)
+			for _, dup := range dups {
+				fmt.Fprintf(os.Stderr, "note: %s was given more than once, fetching it once\n", dup)
+			}
+		}
+		if args[1] == "get" || args[1] == "install" {
+			for _, c := range versionConflicts(args) {
+				fmt.Fprintf(os.Stderr, "warning: %s is given with conflicting versions: %s\n", c.Package, strings.Join(c.Args, ", "))
+			}
+		}
+		if pkgs := argPackages(args); (multi && len(pkgs) > 1) || dryRun {
+			printPlan(os.Stderr, args[1], pkgs)
+			if dryRun {
+				return
+			}
+			ok, err := confirmDestructive(fmt.Sprintf("%s these %d packages?", args[1], len(pkgs)), yes)
+			if err != nil {
+				fatalf(exitNoPerm, "error: %v", err)
+			}
+			if !ok {
+				fatalf(exitNoPerm, "aborted")
+			}
+		}
+		if replaceDir != "" {
+			var pkgs []string
+			for _, arg := range args[2:] {
+				if !strings.HasPrefix(arg, "-") {
+					pkgs = append(pkgs, arg)
+				}
+			}
+			if len(pkgs) != 1 {
+				fatalf(exitUsage, "error: --replace-local requires exactly one package")
+			}
+			modPath := pkgs[0]
+			if idx := strings.LastIndex(modPath, "@"); idx != -1 {
+				modPath = modPath[:idx]
+			}
+			if err := replaceLocal(modPath, replaceDir); err != nil {
+				exitWithError(err)
+			}
 			return
 		}
-		switch args[2] {
-		case "help":
-			fmt.Print(aliasUsage)
+		// Extra arguments are added after expansion, so that they are
+		// never mistaken for aliases.
+		if len(cfg.ExtraArgs) > 0 {
+			args = append(args[:2:2], append(cfg.ExtraArgs, args[2:]...)...)
+		}
+	case "doc":
+		// Only the package argument is expanded; any symbol arguments that
+		// follow it are passed through untouched.
+		for i := 2; i < len(args); i++ {
+			if !strings.HasPrefix(args[i], "-") {
+				res, err := resolveDoc(effective, args[i])
+				if err != nil {
+					fatalf(resolveExitCode(err), "error: %v", err)
+				}
+				args[i] = res.Result
+				used = append(used, res.Chain...)
+				break
+			}
+		}
+	case "x", "run-alias":
+		// Flags must come before the alias, so that the tool's own
+		// arguments are passed through untouched.
+		rest := args[2:]
+		var noInstall bool
+		if len(rest) > 0 && rest[0] == "--no-install" {
+			noInstall = true
+			rest = rest[1:]
+		}
+		if err := runAlias(effective, rest, noInstall); err != nil {
+			exitWithError(err)
+		}
+		return
+	case "installed":
+		if len(args) < 3 || args[2] == "help" {
+			fmt.Print(installedUsage)
 			return
+		}
+		records, err := loadInstalled()
+		if err != nil {
+			fatalf(exitSoftware, "error: %v", err)
+		}
+		switch args[2] {
 		case "list", "ls", "l":
-			type row struct {
-				alias string
-				pkg   string
+			if err := printInstalled(records); err != nil {
+				fatalf(exitSoftware, "error: %v", err)
 			}
-			var rows []row
-			for alias, pkg := range aliases {
-				rows = append(rows, row{alias, pkg})
+		case "sync":
+			if err := syncInstalled(records); err != nil {
+				fatalf(exitSoftware, "error: %v", err)
 			}
-			sort.Slice(rows, func(i, j int) bool {
-				return rows[i].alias < rows[j].alias
-			})
-
-			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-			fmt.Fprintln(tw, "ALIAS\tPACKAGE")
-			fmt.Fprintln(tw, "-----\t-------")
-			for _, row := range rows {
-				fmt.Fprintf(tw, "%s\t%s\n", row.alias, row.pkg)
+		default:
+			fatalf(exitUsage, "error: unknown installed command %q", args[2])
+		}
+		return
+	case "which-go":
+		path, err := exec.LookPath(goBin())
+		if err != nil {
+			fatalf(exitUnavailable, "error: go command %q not found", goBin())
+		}
+		version, err := goVersion()
+		if err != nil {
+			fatalf(exitUnavailable, "error: %v", err)
+		}
+		fmt.Printf("%s %s\n", path, version)
+		return
+	case "version":
+		fmt.Printf("ago %s\n", buildVersion())
+		return
+	case "self-update":
+		if err := selfUpdate(); err != nil {
+			fatalf(exitSoftware, "error: %v", err)
+		}
+		return
+	case "explain":
+		if len(args) < 3 {
+			fatalf(exitUsage, "error: not enough arguments")
+		}
+		results := make([]resolution, 0, len(args)-3)
+		var isValue bool
+		var passThrough bool
+		for _, arg := range args[3:] {
+			passThrough = passThrough || endsPackages(args[2], arg)
+			if isValue || passThrough {
+				isValue = false
+				results = append(results, resolution{Arg: arg, Result: arg})
+				continue
 			}
-			tw.Flush()
-			return
-		case "rm":
-			if len(args) < 4 {
-				fatalf("error: not enough arguments")
+			isValue = takesValue(arg)
+			res, err := resolve(effective, arg)
+			if err != nil {
+				res.Error = err.Error()
+			} else if args[2] == "get" || args[2] == "install" {
+				applyPinnedVersion(effective, &res)
+			}
+			results = append(results, res)
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(results); err != nil {
+			fatalf(exitSoftware, "error: %v", err)
+		}
+		return
+	case "which", "expand":
+		// Only the resolved values are written to standard output, one per
+		// line, so that the output can be used by scripts and go:generate
+		// directives as is.
+		var asJSON bool
+		args, asJSON = cutFlag(args, "json")
+		if len(args) < 3 {
+			fatalf(exitUsage, "error: not enough arguments")
+		}
+		if asJSON {
+			results := make([]whichResult, 0, len(args)-2)
+			var failed bool
+			for _, arg := range args[2:] {
+				r := which(effective, layers, arg)
+				failed = failed || r.Error != ""
+				results = append(results, r)
+				used = append(used, r.chain...)
+			}
+			_ = recordUsage(used)
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.SetEscapeHTML(false)
+			if err := enc.Encode(results); err != nil {
+				fatalf(exitSoftware, "error: %v", err)
 			}
-			delete(aliases, args[3])
-			if err := storeAliases(aliases); err != nil {
-				fatalf("error: %v", err)
+			if failed {
+				os.Exit(exitDataErr)
 			}
-			fmt.Printf("removed alias %q\n", args[3])
 			return
-		default:
-			if len(args) < 4 {
-				fatalf("error: not enough arguments")
+		}
+		used, err = printExpanded(os.Stdout, effective, args[2:])
+		_ = recordUsage(used)
+		if err != nil {
+			fatalf(resolveExitCode(err), "error: %v", err)
+		}
+		return
+	case "init":
+		var force bool
+		args, force = cutFlag(args, "force")
+		if err := initAliases(aliasesPath(), force); err != nil {
+			fatalf(exitIOErr, "error: %v", err)
+		}
+		fmt.Printf("created %s with example aliases; edit it, or add aliases with ago alias\n", aliasesPath())
+		return
+	case "check":
+		if len(args) < 3 {
+			fatalf(exitUsage, "error: not enough arguments")
+		}
+		var failed []string
+		var checked int
+		for i := 3; i < len(args); i++ {
+			arg := args[i]
+			if endsPackages(args[2], arg) {
+				break
+			}
+			if strings.HasPrefix(arg, "-") {
+				if takesValue(arg) {
+					i++
+				}
+				continue
+			}
+			checked++
+			res, err := resolve(effective, arg)
+			switch {
+			case err != nil:
+				failed = append(failed, fmt.Sprintf("%s (%v)", arg, err))
+			case !res.Matched:
+				failed = append(failed, arg)
 			}
-			aliases[args[2]] = args[3]
-			if err := storeAliases(aliases); err != nil {
-				fmt.Fprintf(os.Stderr, "error: %v\n", err)
-				os.Exit(1)
+		}
+		if len(failed) > 0 {
+			fmt.Fprintf(os.Stderr, "%d of %d arguments did not match an alias:\n", len(failed), checked)
+			for _, f := range failed {
+				fmt.Fprintf(os.Stderr, "\t%s\n", f)
 			}
-			fmt.Printf("aliased %q to %q\n", args[2], args[3])
+			os.Exit(exitDataErr)
+		}
+		fmt.Printf("all %d arguments matched an alias\n", checked)
+		return
+	case "env":
+		// Only ago's own settings are handled here; the rest of go env
+		// runs go after the switch.
+		handled, err := runEnv(layers, args[2:])
+		if err != nil {
+			fatalf(exitUsage, "error: %v", err)
+		}
+		if handled {
 			return
 		}
-	}
-
-	fmt.Printf("> go %s\n", strings.Join(args[1:], " "))
-
-	cmd := exec.Command("go", args[1:]...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
-	if err := cmd.Run(); err != nil {
-		var exitErr *exec.ExitError
-		if ok := errors.As(err, &exitErr); ok {
-			os.Exit(exitErr.ExitCode())
+	case "info":
+		if len(args) < 3 {
+			fatalf(exitUsage, "error: not enough arguments")
 		}
-		fatalf("error: %v", err)
-	}
-}
-
-const aliasesFile = "aliases.json"
-
-func loadAliases() (map[string]string, error) {
-	f, err := os.Open(filepath.Join(configDir, aliasesFile))
-	if os.IsNotExist(err) {
-		return make(map[string]string), nil
-	}
-	if err != nil {
-		return nil, fmt.Errorf("open aliases file: %w", err)
-	}
-	defer f.Close()
-
-	var aliases map[string]string
-	if err := json.NewDecoder(f).Decode(&aliases); err != nil {
-		return nil, fmt.Errorf("decode aliases file: %w", err)
-	}
-	return aliases, nil
-}
-
-func storeAliases(aliases map[string]string) error {
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return fmt.Errorf("create config dir: %w", err)
-	}
-	f, err := os.Create(filepath.Join(configDir, aliasesFile))
+		if err := requireNetwork("info"); err != nil {
+			fatalf(exitUnavailable, "error: %v", err)
+		}
+		res, err := resolvePackage(effective, args[2])
+		if err != nil {
+			fatalf(resolveExitCode(err), "error: %v", err)
+		}
+		_ = recordUsage(res.Chain)
+		if err := printModuleInfo(res.Result); err != nil {
+			fatalf(exitUnavailable, "error: %v", err)
+		}
+		return
+	case "profile":
+		if len(args) < 3 || args[2] == "help" {
+			fmt.Print(profileUsage)
+			return
+		}
+		switch args[2] {
+		case "list", "ls", "l":
+			profiles, err := listProfiles()
+			if err != nil {
+				fatalf(exitSoftware, "error: %v", err)
+			}
+			for _, p := range profiles {
+				if p == profile || (p == defaultProfile && profile == "") {
+					fmt.Printf("* %s\n", p)
+				} else {
+					fmt.Printf("  %s\n", p)
+				}
+			}
+			return
+		default:
+			fatalf(exitUsage, "error: unknown profile command %q", args[2])
+		}
+	case "alias", "a":
+		if len(args) < 3 {
+			fmt.Print(aliasUsage)
+			return
+		}
+		// ago alias add, or -- before the name, creates an alias even if it
+		// is named after a subcommand, such as an alias named lint.
+		sub := args[2]
+		if sub == "add" || sub == "--" {
+			args = append(args[:2:2], args[3:]...)
+			if len(args) < 3 {
+				fatalf(exitUsage, "error: not enough arguments")
+			}
+			sub = ""
+		}
+		switch sub {
+		case "help":
+			checkAliasArgs(args, 0)
+			fmt.Print(aliasUsage)
+			return
+		case "completion-data":
+			var desc, null bool
+			args, desc = cutFlag(args, "desc")
+			args, null = cutFlag(args, "null")
+			checkAliasArgs(args, 0)
+			if err := writeCompletionData(os.Stdout, effective, desc, null); err != nil {
+				fatalf(exitIOErr, "error: %v", err)
+			}
+			return
+		case "list", "ls", "l", "count", "packages":
+			var opts listOptions
+			args, opts.JSON = cutFlag(args, "json")
+			args, opts.NoTruncate = cutFlag(args, "no-truncate")
+			args, opts.Count = cutFlag(args, "count")
+			args, opts.Long = cutFlag(args, "long")
+			args, opts.Compact = cutFlag(args, "compact")
+			args, opts.Packages = cutFlag(args, "packages")
+			opts.Packages = opts.Packages || args[2] == "packages"
+			opts.Resolve = effective
+			opts.Count = opts.Count || args[2] == "count"
+			var prefix, pattern, columns string
+			if args, prefix, err = cutFlagValue(args, "prefix"); err != nil {
+				fatalf(exitUsage, "error: %v", err)
+			}
+			if args, columns, err = cutFlagValue(args, "columns"); err != nil {
+				fatalf(exitUsage, "error: %v", err)
+			}
+			if columns != "" {
+				if opts.Columns, err = parseColumns(columns); err != nil {
+					fatalf(exitUsage, "error: %v", err)
+				}
+			}
+			var tmpl string
+			if args, tmpl, err = cutFlagValue(args, "template"); err != nil {
+				fatalf(exitUsage, "error: %v", err)
+			}
+			if tmpl != "" {
+				if opts.Template, err = parseListTemplate(tmpl); err != nil {
+					fatalf(exitUsage, "error: %v", err)
+				}
+			}
+			var sinceFlag string
+			if args, sinceFlag, err = cutFlagValue(args, "since"); err != nil {
+				fatalf(exitUsage, "error: %v", err)
+			}
+			checkAliasArgs(args, 1)
+			if len(args) > 3 {
+				pattern = args[3]
+			}
+			filtered, err := filterAliases(aliases, prefix, pattern)
+			if err != nil {
+				fatalf(exitUsage, "error: %v", err)
+			}
+			if sinceFlag != "" {
+				since, err := parseSince(sinceFlag)
+				if err != nil {
+					fatalf(exitUsage, "error: %v", err)
+				}
+				modified, err := loadModified(aliasesPath())
+				if err != nil {
+					fatalf(exitConfig, "error: %v", err)
+				}
+				filtered = modifiedSince(filtered, modified, since)
+			}
+			if err := printAliasList(filtered, opts); err != nil {
+				fatalf(exitSoftware, "error: %v", err)
+			}
+			return
+		case "show":
+			checkAliasArgs(args, 1)
+			if len(args) < 4 {
+				fatalf(exitUsage, "error: not enough arguments")
+			}
+			entry, ok := aliases[args[3]]
+			if !ok {
+				fatalf(exitNoInput, "error: alias %q does not exist", args[3])
+			}
+			if err := printAlias(args[3], entry); err != nil {
+				fatalf(exitSoftware, "error: %v", err)
+			}
+			return
+		case "open":
+			var printOnly bool
+			args, printOnly = cutFlag(args, "print")
+			checkAliasArgs(args, 1)
+			if len(args) < 4 {
+				fatalf(exitUsage, "error: not enough arguments")
+			}
+			entry, ok := effective[args[3]]
+			if !ok {
+				fatalf(exitNoInput, "error: alias %q does not exist", args[3])
+			}
+			if entry.isBundle() {
+				fatalf(exitDataErr, "error: alias %q is a bundle of several packages", args[3])
+			}
+			pkg, err := expandArg(effective, args[3])
+			if err != nil {
+				fatalf(resolveExitCode(err), "error: %v", err)
+			}
+			url, err := repoURL(pkg)
+			if err != nil {
+				fatalf(exitDataErr, "error: %v", err)
+			}
+			if printOnly {
+				fmt.Println(url)
+				return
+			}
+			if err := openBrowser(url); err != nil {
+				fatalf(exitUnavailable, "error: %v", err)
+			}
+			return
+		case "graph":
+			var dot bool
+			args, dot = cutFlag(args, "dot")
+			checkAliasArgs(args, 0)
+			if dot {
+				printAliasDot(os.Stdout, effective)
+			} else {
+				printAliasGraph(os.Stdout, effective)
+			}
+			return
+		case "desc", "touch-desc":
+			if len(args) < 5 {
+				fatalf(exitUsage, "error: not enough arguments")
+			}
+			entry, ok := aliases[args[3]]
+			if !ok {
+				fatalf(exitNoInput, "error: alias %q does not exist", args[3])
+			}
+			prev := entry.Description
+			entry.Description = strings.TrimSpace(strings.Join(args[4:], " "))
+			aliases[args[3]] = entry
+			if err := storeAliases(aliasesPath(), aliases); err != nil {
+				fatalf(exitIOErr, "error: %v", err)
+			}
+			warnHistory(historyEntry{Action: historyDescribe, Alias: args[3], Package: entry.target(), Previous: prev, Description: entry.Description})
+			if entry.Description == "" {
+				fmt.Printf("cleared the description of alias %q\n", args[3])
+			} else {
+				fmt.Printf("described alias %q as %q\n", args[3], entry.Description)
+			}
+			return
+		case "import":
+			var dedupe, prune, dryRun, yes bool
+			args, dedupe = cutFlag(args, "dedupe")
+			args, prune = cutFlag(args, "prune-missing")
+			args, dryRun = cutFlag(args, "dry-run")
+			args, yes = cutFlag(args, "yes")
+			var keep string
+			if args, keep, err = cutFlagValue(args, "keep"); err != nil {
+				fatalf(exitUsage, "error: %v", err)
+			}
+			if keep == "" {
+				keep = keepAll
+			}
+			if !validKeep(keep) {
+				fatalf(exitUsage, "error: invalid --keep %q; want shortest, first or all", keep)
+			}
+			var transform string
+			if args, transform, err = cutFlagValue(args, "transform"); err != nil {
+				fatalf(exitUsage, "error: %v", err)
+			}
+			var re *regexp.Regexp
+			var repl string
+			if transform != "" {
+				if re, repl, err = parseKeyTransform(transform); err != nil {
+					fatalf(exitUsage, "error: %v", err)
+				}
+			}
+			checkAliasArgs(args, 1)
+			if len(args) < 4 {
+				fatalf(exitUsage, "error: not enough arguments")
+			}
+			imported, err := importAliases(args[3])
+			if err != nil {
+				fatalf(exitNoInput, "error: %v", err)
+			}
+			if re != nil {
+				renames, err := planRenames(imported, re, repl)
+				if err != nil {
+					fatalf(exitDataErr, "error: %v", err)
+				}
+				applyRenames(imported, renames)
+				for _, r := range renames {
+					fmt.Printf("importing %q as %q\n", r.From, r.To)
+				}
+			}
+			existing := mergeAliases(aliases)
+			for alias, entry := range imported {
+				aliases[alias] = entry
+			}
+			// Pruning aliases missing from the import mirrors it.
+			pruned := make(map[string]aliasEntry)
+			if prune {
+				for name, entry := range aliases {
+					if _, ok := imported[name]; !ok {
+						pruned[name] = entry
+						delete(aliases, name)
+					}
+				}
+			}
+			var removed map[string]aliasEntry
+			if dedupe {
+				removed = dedupeAliases(aliases, keep, existing)
+			}
+			if dryRun {
+				for _, name := range sortedNames(pruned) {
+					fmt.Printf("would remove alias %q, missing from %s\n", name, args[3])
+				}
+				fmt.Printf("would import %d aliases\n", len(imported))
+				return
+			}
+			if len(pruned) > 0 {
+				ask, err := shouldConfirm(yes)
+				if err != nil {
+					fatalf(exitNoPerm, "error: %v", err)
+				}
+				if ask {
+					for _, name := range sortedNames(pruned) {
+						fmt.Fprintf(os.Stderr, "  %s\t%s\n", name, pruned[name].target())
+					}
+					if !confirm(fmt.Sprintf("remove these %d aliases, missing from %s?", len(pruned), args[3])) {
+						fatalf(exitNoPerm, "aborted")
+					}
+				}
+			}
+			if err := storeAliases(aliasesPath(), aliases); err != nil {
+				fatalf(exitIOErr, "error: %v", err)
+			}
+			gone := mergeAliases(pruned, removed)
+			for _, name := range sortedNames(gone) {
+				warnHistory(historyEntry{Action: historyRemove, Alias: name, Previous: gone[name].target()})
+			}
+			fmt.Printf("imported %d aliases\n", len(imported))
+			if len(pruned) > 0 {
+				fmt.Printf("removed %d aliases missing from %s\n", len(pruned), args[3])
+			}
+			if len(removed) > 0 {
+				fmt.Printf("removed %d duplicate aliases\n", len(removed))
+			}
+			return
+		case "diff":
+			var asJSON bool
+			args, asJSON = cutFlag(args, "json")
+			checkAliasArgs(args, 1)
+			if len(args) < 4 {
+				fatalf(exitUsage, "error: not enough arguments")
+			}
+			other, err := importAliases(args[3])
+			if err != nil {
+				fatalf(exitNoInput, "error: %v", err)
+			}
+			if err := printAliasDiff(diffAliases(aliases, other), asJSON); err != nil {
+				fatalf(exitSoftware, "error: %v", err)
+			}
+			return
+		case "resolve-all", "effective":
+			var asJSON bool
+			args, asJSON = cutFlag(args, "json")
+			checkAliasArgs(args, 0)
+			if err := printEffective(layers, asJSON); err != nil {
+				fatalf(exitSoftware, "error: %v", err)
+			}
+			return
+		case "export":
+			checkAliasArgs(args, 0)
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(aliases); err != nil {
+				fatalf(exitSoftware, "error: %v", err)
+			}
+			return
+		case "history":
+			checkAliasArgs(args, 1)
+			var name string
+			if len(args) > 3 {
+				name = args[3]
+			}
+			entries, err := loadHistory(name)
+			if err != nil {
+				fatalf(exitSoftware, "error: %v", err)
+			}
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "TIME\tACTION\tALIAS\tPACKAGE")
+			fmt.Fprintln(tw, "----\t------\t-----\t-------")
+			for _, e := range entries {
+				pkg := e.Package
+				switch e.Action {
+				case historyRetarget:
+					pkg = e.Previous + " -> " + e.Package
+				case historyRemove:
+					pkg = e.Previous
+				case historyRename:
+					pkg = e.Package + " (renamed from " + e.Previous + ")"
+				case historyDescribe:
+					pkg = fmt.Sprintf("%s (described as %q)", e.Package, e.Description)
+					if e.Description == "" {
+						pkg = e.Package + " (description cleared)"
+					}
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", e.Time.Local().Format("2006-01-02 15:04:05"), e.Action, e.Alias, pkg)
+			}
+			tw.Flush()
+			return
+		case "deps":
+			checkAliasArgs(args, 1)
+			var path string
+			if len(args) > 3 {
+				path = args[3]
+			}
+			if err := printAliasDeps(aliases, path); err != nil {
+				fatalf(exitNoInput, "error: %v", err)
+			}
+			return
+		case "lint":
+			var fix bool
+			args, fix = cutFlag(args, "fix")
+			checkAliasArgs(args, 0)
+			cleaned, problems := lintAliases(aliases)
+			for _, p := range problems {
+				fmt.Println(p)
+			}
+			if len(problems) == 0 {
+				fmt.Println("no problems found")
+				return
+			}
+			if !fix {
+				fatalf(exitDataErr, "found %d problems; run ago alias lint --fix to fix them", len(problems))
+			}
+			if err := storeAliases(aliasesPath(), cleaned); err != nil {
+				fatalf(exitIOErr, "error: %v", err)
+			}
+			fmt.Printf("fixed %d problems\n", len(problems))
+			return
+		case "normalize-major":
+			var dryRun bool
+			args, dryRun = cutFlag(args, "dry-run")
+			checkAliasArgs(args, 0)
+			changes := normalizeAliasMajors(aliases)
+			if len(changes) == 0 {
+				fmt.Println("all major versions are already normalized")
+				return
+			}
+			for _, c := range changes {
+				if dryRun {
+					fmt.Printf("would rewrite alias %q from %s to %s\n", c.Alias, c.From, c.To)
+				} else {
+					fmt.Printf("rewrote alias %q from %s to %s\n", c.Alias, c.From, c.To)
+				}
+			}
+			if dryRun {
+				return
+			}
+			if err := storeAliases(aliasesPath(), aliases); err != nil {
+				fatalf(exitIOErr, "error: %v", err)
+			}
+			for _, c := range changes {
+				if !aliases[c.Alias].isBundle() {
+					warnHistory(historyEntry{Action: historyRetarget, Alias: c.Alias, Package: c.To, Previous: c.From})
+				}
+			}
+			return
+		case "sort":
+			checkAliasArgs(args, 0)
+			if err := storeAliases(aliasesPath(), aliases); err != nil {
+				fatalf(exitIOErr, "error: %v", err)
+			}
+			fmt.Printf("sorted %s\n", aliasesPath())
+			return
+		case "backup":
+			checkAliasArgs(args, 1)
+			if len(args) > 3 && args[3] == "list" {
+				backups, err := listBackups()
+				if err != nil {
+					fatalf(exitSoftware, "error: %v", err)
+				}
+				for _, name := range backups {
+					fmt.Println(name)
+				}
+				return
+			}
+			var name string
+			if len(args) > 3 {
+				name = args[3]
+			}
+			name, err := backupAliases(name)
+			if err != nil {
+				fatalf(exitIOErr, "error: %v", err)
+			}
+			fmt.Printf("created backup %q\n", name)
+			return
+		case "restore":
+			var yes bool
+			args, yes = cutFlag(args, "yes")
+			checkAliasArgs(args, 1)
+			if len(args) < 4 {
+				fatalf(exitUsage, "error: not enough arguments")
+			}
+			if ok, err := confirmDestructive(fmt.Sprintf("replace %s with backup %q?", aliasesPath(), args[3]), yes); err != nil {
+				fatalf(exitNoPerm, "error: %v", err)
+			} else if !ok {
+				fatalf(exitNoPerm, "aborted")
+			}
+			if err := restoreAliases(args[3]); err != nil {
+				fatalf(exitNoInput, "error: %v", err)
+			}
+			fmt.Printf("restored backup %q\n", args[3])
+			return
+		case "check-updates", "outdated":
+			checkAliasArgs(args, 0)
+			checks := checkUpdates(aliases)
+			if len(checks) == 0 {
+				fmt.Println("no aliases have a pinned version")
+				return
+			}
+			if err := printUpdates(checks); err != nil {
+				fatalf(exitSoftware, "error: %v", err)
+			}
+			return
+		case "bump", "touch":
+			var yes bool
+			args, yes = cutFlag(args, "yes")
+			var to string
+			if args, to, err = cutFlagValue(args, "to"); err != nil {
+				fatalf(exitUsage, "error: %v", err)
+			}
+			checkAliasArgs(args, 1)
+			if len(args) < 4 {
+				fatalf(exitUsage, "error: not enough arguments")
+			}
+			name := args[3]
+			entry, ok := aliases[name]
+			if !ok {
+				fatalf(exitNoInput, "error: alias %q does not exist", name)
+			}
+			if err := bumpable(entry); err != nil {
+				fatalf(exitDataErr, "error: %v", err)
+			}
+			major := -1
+			if to != "" {
+				if major, err = parseMajor(to); err != nil {
+					fatalf(exitUsage, "error: %v", err)
+				}
+			}
+			pkg, err := bumpedPackage(entry, major)
+			if err != nil {
+				fatalf(exitUnavailable, "error: %v", err)
+			}
+			if pkg == entry.Package {
+				fmt.Printf("alias %q is already at %s\n", name, pkg)
+				return
+			}
+			if ok, err := confirmDestructive(fmt.Sprintf("retarget alias %q from %s to %s?", name, entry.Package, pkg), yes); err != nil {
+				fatalf(exitNoPerm, "error: %v", err)
+			} else if !ok {
+				fatalf(exitNoPerm, "aborted")
+			}
+			prev := entry.Package
+			entry.Package = pkg
+			aliases[name] = entry
+			if err := storeAliases(aliasesPath(), aliases); err != nil {
+				fatalf(exitIOErr, "error: %v", err)
+			}
+			warnHistory(historyEntry{Action: historyRetarget, Alias: name, Package: pkg, Previous: prev})
+			fmt.Printf("aliased %q to %q\n", name, pkg)
+			return
+		case "swap":
+			checkAliasArgs(args, 2)
+			if len(args) != 5 {
+				fatalf(exitUsage, "error: want exactly two aliases")
+			}
+			a, b := args[3], args[4]
+			for _, name := range []string{a, b} {
+				if _, ok := aliases[name]; !ok {
+					fatalf(exitNoInput, "error: alias %q does not exist", name)
+				}
+			}
+			if a == b {
+				fatalf(exitUsage, "error: cannot swap alias %q with itself", a)
+			}
+			aliases[a], aliases[b] = aliases[b], aliases[a]
+			if err := storeAliases(aliasesPath(), aliases); err != nil {
+				fatalf(exitIOErr, "error: %v", err)
+			}
+			warnHistory(historyEntry{Action: historyRetarget, Alias: a, Package: aliases[a].target(), Previous: aliases[b].target()})
+			warnHistory(historyEntry{Action: historyRetarget, Alias: b, Package: aliases[b].target(), Previous: aliases[a].target()})
+			fmt.Printf("aliased %q to %q\n", a, aliases[a].target())
+			fmt.Printf("aliased %q to %q\n", b, aliases[b].target())
+			return
+		case "rename", "mv":
+			var useRegex, dryRun bool
+			args, useRegex = cutFlag(args, "regex")
+			args, dryRun = cutFlag(args, "dry-run")
+			checkAliasArgs(args, 2)
+			if len(args) < 5 {
+				fatalf(exitUsage, "error: not enough arguments")
+			}
+			var renames []aliasRename
+			if useRegex {
+				re, err := regexp.Compile(args[3])
+				if err != nil {
+					fatalf(exitUsage, "error: invalid pattern: %v", err)
+				}
+				if renames, err = planRenames(aliases, re, args[4]); err != nil {
+					fatalf(exitDataErr, "error: %v", err)
+				}
+			} else {
+				if _, ok := aliases[args[3]]; !ok {
+					fatalf(exitNoInput, "error: alias %q does not exist", args[3])
+				}
+				renames = []aliasRename{{From: args[3], To: args[4]}}
+				if err := checkRenames(aliases, renames); err != nil {
+					fatalf(exitDataErr, "error: %v", err)
+				}
+			}
+			if len(renames) == 0 {
+				fmt.Println("no aliases match")
+				return
+			}
+			preview := mergeAliases(aliases)
+			refs := updateRefs(preview, renames)
+			if dryRun {
+				for _, r := range renames {
+					fmt.Printf("would rename %q to %q\n", r.From, r.To)
+				}
+				for _, name := range refs {
+					fmt.Printf("would update %q to %q\n", name, preview[name].target())
+				}
+				return
+			}
+			broken := brokenRefs(project.Aliases, aliases, renames)
+			prevRefs := make(map[string]string, len(refs))
+			for _, name := range refs {
+				prevRefs[name] = aliases[name].target()
+			}
+			updateRefs(aliases, renames)
+			applyRenames(aliases, renames)
+			if err := storeAliases(aliasesPath(), aliases); err != nil {
+				fatalf(exitIOErr, "error: %v", err)
+			}
+			for _, r := range renames {
+				warnHistory(historyEntry{Action: historyRename, Alias: r.To, Package: aliases[r.To].target(), Previous: r.From})
+				fmt.Printf("renamed %q to %q\n", r.From, r.To)
+			}
+			for _, name := range refs {
+				prev := prevRefs[name]
+				for _, r := range renames {
+					if r.From == name {
+						name = r.To
+					}
+				}
+				warnHistory(historyEntry{Action: historyRetarget, Alias: name, Package: aliases[name].target(), Previous: prev})
+				fmt.Printf("updated %q to %q\n", name, aliases[name].target())
+			}
+			if len(broken) > 0 {
+				fmt.Fprintf(os.Stderr, "warning: project aliases %s still refer to the old names\n", strings.Join(broken, ", "))
+			}
+			fmt.Fprintln(os.Stderr, "note: shell completion caches and scripts may still use the old names; regenerate them to pick up the new ones")
+			return
+		case "rm-target":
+			var yes, dryRun, prefix bool
+			args, yes = cutFlag(args, "yes")
+			args, dryRun = cutFlag(args, "dry-run")
+			args, prefix = cutFlag(args, "prefix")
+			checkAliasArgs(args, 1)
+			if len(args) != 4 {
+				fatalf(exitUsage, "error: want exactly one package")
+			}
+			matched := aliasesByTarget(aliases, args[3], prefix)
+			if len(matched) == 0 {
+				fmt.Println("no aliases match")
+				return
+			}
+			names := sortedNames(matched)
+			if dryRun {
+				for _, name := range names {
+					fmt.Printf("would remove alias %q (%s)\n", name, matched[name].Package)
+				}
+				return
+			}
+			ask, err := shouldConfirm(yes)
+			if err != nil {
+				fatalf(exitNoPerm, "error: %v", err)
+			}
+			if ask {
+				for _, name := range names {
+					fmt.Fprintf(os.Stderr, "  %s\t%s\n", name, matched[name].Package)
+				}
+				if !confirm(fmt.Sprintf("remove these %d aliases?", len(names))) {
+					fatalf(exitNoPerm, "aborted")
+				}
+			}
+			for _, name := range names {
+				delete(aliases, name)
+			}
+			if err := storeAliases(aliasesPath(), aliases); err != nil {
+				fatalf(exitIOErr, "error: %v", err)
+			}
+			for _, name := range names {
+				warnHistory(historyEntry{Action: historyRemove, Alias: name, Previous: matched[name].Package})
+				fmt.Printf("removed alias %q (%s)\n", name, matched[name].Package)
+			}
+			return
+		case "rm":
+			var yes, dryRun bool
+			args, yes = cutFlag(args, "yes")
+			args, dryRun = cutFlag(args, "dry-run")
+			var prefix, pattern string
+			if args, prefix, err = cutFlagValue(args, "prefix"); err != nil {
+				fatalf(exitUsage, "error: %v", err)
+			}
+			if args, pattern, err = cutFlagValue(args, "glob"); err != nil {
+				fatalf(exitUsage, "error: %v", err)
+			}
+			var unused bool
+			args, unused = cutFlag(args, "unused")
+			var sinceFlag string
+			if args, sinceFlag, err = cutFlagValue(args, "since"); err != nil {
+				fatalf(exitUsage, "error: %v", err)
+			}
+			var since time.Duration
+			if sinceFlag != "" {
+				if !unused {
+					fatalf(exitUsage, "error: --since can only be used with --unused")
+				}
+				if since, err = parseSince(sinceFlag); err != nil {
+					fatalf(exitUsage, "error: %v", err)
+				}
+			}
+			names := args[3:]
+			group := prefix != "" || pattern != "" || unused
+			if group {
+				if len(names) > 0 {
+					fatalf(exitUsage, "error: aliases cannot be named with --prefix, --glob or --unused")
+				}
+				matched, err := filterAliases(aliases, prefix, pattern)
+				if err != nil {
+					fatalf(exitUsage, "error: %v", err)
+				}
+				if unused {
+					usage, err := loadUsage()
+					if err != nil {
+						fatalf(exitConfig, "error: %v", err)
+					}
+					matched = unusedAliases(matched, usage, since)
+				}
+				if len(matched) == 0 {
+					fmt.Println("no aliases match")
+					return
+				}
+				names = sortedNames(matched)
+			}
+			if len(names) == 0 {
+				fatalf(exitUsage, "error: not enough arguments")
+			}
+			if dryRun {
+				for _, name := range names {
+					fmt.Printf("would remove alias %q\n", name)
+				}
+				return
+			}
+			ask, err := shouldConfirm(yes)
+			if err != nil {
+				fatalf(exitNoPerm, "error: %v", err)
+			}
+			if ask {
+				question := fmt.Sprintf("remove alias %q?", names[0])
+				if len(names) > 1 {
+					for _, name := range names {
+						fmt.Fprintf(os.Stderr, "  %s\t%s\n", name, aliases[name].target())
+					}
+					question = fmt.Sprintf("remove these %d aliases?", len(names))
+				}
+				if !confirm(question) {
+					fatalf(exitNoPerm, "aborted")
+				}
+			}
+			removed := make(map[string]aliasEntry)
+			for _, name := range names {
+				if prev, existed := aliases[name]; existed {
+					removed[name] = prev
+				}
+				delete(aliases, name)
+			}
+			if err := storeAliases(aliasesPath(), aliases); err != nil {
+				fatalf(exitIOErr, "error: %v", err)
+			}
+			for _, name := range names {
+				if prev, existed := removed[name]; existed {
+					warnHistory(historyEntry{Action: historyRemove, Alias: name, Previous: prev.target()})
+				}
+				fmt.Printf("removed alias %q\n", name)
+			}
+			return
+		default:
+			var minGo, version string
+			if args, minGo, err = cutFlagValue(args, "min-go"); err != nil {
+				fatalf(exitUsage, "error: %v", err)
+			}
+			if args, version, err = cutFlagValue(args, "version"); err != nil {
+				fatalf(exitUsage, "error: %v", err)
+			}
+			var rawMajor, exact, yes bool
+			args, rawMajor = cutFlag(args, "raw-major")
+			args, exact = cutFlag(args, "exact")
+			args, yes = cutFlag(args, "yes")
+			var desc, tags string
+			if args, desc, err = cutFlagValue(args, "desc"); err != nil {
+				fatalf(exitUsage, "error: %v", err)
+			}
+			if args, tags, err = cutFlagValue(args, "tags"); err != nil {
+				fatalf(exitUsage, "error: %v", err)
+			}
+			var last bool
+			if args, last = cutFlag(args, "last"); last {
+				if len(args) > 3 {
+					fatalf(exitUsage, "error: a package cannot be given with --last")
+				}
+				pkg, err := lastPackage()
+				if err != nil {
+					fatalf(exitNoInput, "error: %v", err)
+				}
+				args = append(args, pkg)
+			}
+			if len(args) < 4 {
+				fatalf(exitUsage, "error: not enough arguments")
+			}
+			if strings.TrimSpace(args[2]) == "" {
+				fatalf(exitDataErr, "error: alias name must not be empty")
+			}
+			if minGo != "" && !validGoVersion(minGo) {
+				fatalf(exitDataErr, "error: invalid go version %q", minGo)
+			}
+			entry := aliasEntry{
+				Version:     strings.TrimPrefix(version, "@"),
+				MinGo:       minGo,
+				RawMajor:    rawMajor,
+				ExactMatch:  exact,
+				Description: desc,
+				Tags:        splitTags(tags),
+			}
+			// More than one package makes a bundle.
+			if len(args) > 4 {
+				entry.Bundle = args[3:]
+			} else {
+				entry.Package = args[3]
+			}
+			if entry.Version != "" && (isPathAlias(entry.Package) || hasScheme(entry.Package)) {
+				fatalf(exitDataErr, "error: %s is not a module path and cannot have a version", entry.Package)
+			}
+			prev, existed := aliases[args[2]]
+			if existed && prev.target() != entry.target() {
+				question := fmt.Sprintf("retarget alias %q from %s to %s?", args[2], prev.target(), entry.target())
+				if ok, err := confirmDestructive(question, yes); err != nil {
+					fatalf(exitNoPerm, "error: %v", err)
+				} else if !ok {
+					fatalf(exitNoPerm, "aborted")
+				}
+			}
+			aliases[args[2]] = entry
+			if err := storeAliases(aliasesPath(), aliases); err != nil {
+				fatalf(exitIOErr, "error: %v", err)
+			}
+			if !existed {
+				warnHistory(historyEntry{Action: historyAdd, Alias: args[2], Package: entry.target()})
+			} else if prev.target() != entry.target() {
+				warnHistory(historyEntry{Action: historyRetarget, Alias: args[2], Package: entry.target(), Previous: prev.target()})
+			}
+			fmt.Printf("aliased %q to %q\n", args[2], entry.target())
+			return
+		}
+	case "work":
+		// go work use takes directories, so only path aliases are
+		// expanded; a module path would be meaningless there.
+		if len(args) > 2 && args[2] == "use" {
+			dirs := pathAliases(effective)
+			for i := 3; i < len(args) && !endsPackages(args[1], args[i]); i++ {
+				if !strings.HasPrefix(args[i], "-") {
+					res, err := resolvePackage(dirs, args[i])
+					if err != nil {
+						fatalf(resolveExitCode(err), "error: %v", err)
+					}
+					args[i] = res.Result
+					used = append(used, res.Chain...)
+				}
+			}
+		}
+	default:
+		if forceExpand {
+			for i := 2; i < len(args) && !endsPackages(args[1], args[i]); i++ {
+				if !strings.HasPrefix(args[i], "-") {
+					res, err := resolvePackage(effective, args[i])
+					if err != nil {
+						fatalf(resolveExitCode(err), "error: %v", err)
+					}
+					args[i] = res.Result
+					used = append(used, res.Chain...)
+				}
+			}
+		}
+	}
+
+	// A pre hook can veto the command, while a post hook only reacts to
+	// its result.
+	if err := runHook("pre", args[1], args[2:], 0); err != nil {
+		fatalf(exitSoftware, "error: aborted by %v", err)
+	}
+	run := runGo
+	if hints {
+		run = runGoWithHints
+	}
+	if cfg.Retries > 0 && (args[1] == "get" || args[1] == "install") {
+		run = runGoWithRetries(cfg.Retries, hints)
+	}
+	err = run(args[1:]...)
+	if err := runHook("post", args[1], args[2:], exitStatus(err)); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
 	if err != nil {
-		return fmt.Errorf("create aliases file: %w", err)
+		exitWithError(err)
+	}
+	if tidy {
+		if err := runGo("mod", "tidy"); err != nil {
+			exitWithError(err)
+		}
 	}
-	defer f.Close()
 
-	enc := json.NewEncoder(f)
-	enc.SetIndent("", "  ")
-	if err := enc.Encode(aliases); err != nil {
-		return fmt.Errorf("encode aliases file: %w", err)
+	if len(pins) > 0 {
+		if err := updatePinnedVersions(aliases, pins, repackages); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		}
+	}
+	// Like the last package below, usage only helps later alias commands.
+	_ = recordUsage(used)
+	if args[1] == "get" || args[1] == "install" {
+		// This only helps later alias commands, so failing to record the
+		// package shouldn't fail the command.
+		_ = recordLastPackage(args[2:])
+	}
+	if len(installs) > 0 {
+		if err := recordInstalls(installs); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		}
+	}
+}
+
+// updatePinnedVersions pins each alias of pins to its version, as given to a
+// successful ago get --update-aliases, and moves each alias of packages, those
+// bumped to a new major version, to its package. Only aliases of the aliases
+// file can be updated.
+func updatePinnedVersions(aliases map[string]aliasEntry, pins, packages map[string]string) error {
+	names := make([]string, 0, len(pins))
+	for name := range pins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var changed bool
+	var retargets []historyEntry
+	for _, name := range names {
+		version := pins[name]
+		entry, ok := aliases[name]
+		switch {
+		case !ok:
+			fmt.Fprintf(os.Stderr, "note: alias %q is not in the aliases file, so its version is not updated\n", name)
+		default:
+			if pkg, ok := packages[name]; ok && entry.Package != pkg {
+				retargets = append(retargets, historyEntry{Action: historyRetarget, Alias: name, Package: pkg, Previous: entry.Package})
+				entry.Package = pkg
+				aliases[name] = entry
+				changed = true
+				fmt.Printf("moved alias %q to %s\n", name, pkg)
+			}
+			if entry.Version != version {
+				entry.Version = version
+				aliases[name] = entry
+				changed = true
+				fmt.Printf("pinned alias %q to %s\n", name, version)
+			}
+		}
+	}
+	if !changed {
+		return nil
+	}
+	if err := storeAliases(aliasesPath(), aliases); err != nil {
+		return fmt.Errorf("update pinned versions: %w", err)
+	}
+	for _, entry := range retargets {
+		warnHistory(entry)
 	}
 	return nil
 }
 
-func fatalf(format string, args ...interface{}) {
-	if !strings.HasSuffix(format, "\n") {
-		format += "\n"
+// dedupArgs removes repeated packages from the arguments following the
+// command in args, keeping the first occurrence of each. Flags and their
+// values are never removed. It returns the remaining arguments and the removed
+// packages.
+func dedupArgs(args []string) (rest, dups []string) {
+	seen := make(map[string]bool)
+	rest = args[:2:2]
+	var isValue bool
+	for i, arg := range args[2:] {
+		switch {
+		case endsPackages(args[1], arg):
+			return append(rest, args[2+i:]...), dups
+		case isValue:
+			isValue = false
+		case takesValue(arg):
+			isValue = true
+		case !strings.HasPrefix(arg, "-"):
+			if seen[arg] {
+				dups = append(dups, arg)
+				continue
+			}
+			seen[arg] = true
+		}
+		rest = append(rest, arg)
+	}
+	return rest, dups
+}
+
+// versionConflict is a package given with more than one version.
+type versionConflict struct {
+	Package string
+	// Args are the arguments naming the package, in the order given.
+	Args []string
+}
+
+// versionConflicts returns the packages given more than once with different
+// versions in the arguments following the command in args, which are expected
+// to have been expanded, sorted by package. A package given without a version
+// conflicts with any version of it, and a package at another major version,
+// such as foo/v2, counts as the same package.
+func versionConflicts(args []string) []versionConflict {
+	versions := make(map[string][]string)
+	var isValue bool
+	for _, arg := range args[2:] {
+		if endsPackages(args[1], arg) {
+			break
+		}
+		switch {
+		case isValue:
+			isValue = false
+			continue
+		case takesValue(arg):
+			isValue = true
+			continue
+		case strings.HasPrefix(arg, "-"):
+			continue
+		}
+		pkg, _, _ := strings.Cut(arg, "@")
+		base, _ := splitMajor(pkg)
+		if !containsString(versions[base], arg) {
+			versions[base] = append(versions[base], arg)
+		}
+	}
+	var conflicts []versionConflict
+	for base, vs := range versions {
+		if len(vs) > 1 {
+			conflicts = append(conflicts, versionConflict{Package: base, Args: vs})
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Package < conflicts[j].Package
+	})
+	return conflicts
+}
+
+// containsString reports whether list contains s.
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// readPackageList reads a list of packages or aliases from the file at path,
+// one per line. Blank lines and comments starting with # are skipped.
+func readPackageList(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read package list: %w", err)
+	}
+	var pkgs []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if idx := strings.IndexByte(line, '#'); idx != -1 {
+			line = line[:idx]
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			pkgs = append(pkgs, line)
+		}
+	}
+	return pkgs, nil
+}
+
+// goCommand returns a command running go with args, connected to the standard
+// streams.
+func goCommand(args ...string) *exec.Cmd {
+	cmd := exec.Command(goBin(), args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin
+	env := goEnvExtra
+	if offline {
+		// Restrict the go command to the module cache.
+		env = append(env, "GOPROXY=off")
 	}
-	fmt.Fprintf(os.Stderr, format, args...)
-	os.Exit(1)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	return cmd
+}
+
+// runGo prints the go command with args, then runs it.
+func runGo(args ...string) error {
+	fmt.Printf("> go %s\n", strings.Join(args, " "))
+	return goCommand(args...).Run()
+}
+
+// goOutput holds what a go command run by runGoCapture wrote.
+type goOutput struct {
+	Stdout, Stderr bytes.Buffer
+}
+
+// runGoCapture runs the go command with args like runGo, but also copies its
+// standard output and error to the returned goOutput, for features that need
+// to inspect them. The output is still shown as it is written. Commands that
+// don't need their output inspected should use runGo, which doesn't copy it.
+func runGoCapture(args ...string) (*goOutput, error) {
+	fmt.Printf("> go %s\n", strings.Join(args, " "))
+	var out goOutput
+	cmd := goCommand(args...)
+	cmd.Stdout = io.MultiWriter(os.Stdout, &out.Stdout)
+	cmd.Stderr = io.MultiWriter(os.Stderr, &out.Stderr)
+	err := cmd.Run()
+	return &out, err
+}
+
+// replaceLocal points the current module's requirement on the module at
+// modPath to the directory dir, by adding a replace directive, and tidies
+// the module.
+func replaceLocal(modPath, dir string) error {
+	out, err := exec.Command(goBin(), "env", "GOMOD").Output()
+	if err != nil {
+		return fmt.Errorf("go env GOMOD: %w", err)
+	}
+	if gomod := strings.TrimSpace(string(out)); gomod == "" || gomod == os.DevNull {
+		return errors.New("--replace-local must be run inside a module, but no go.mod was found")
+	}
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("resolve local path: %w", err)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	if err := runGo("mod", "edit", "-replace", modPath+"="+dir); err != nil {
+		return err
+	}
+	return runGo("mod", "tidy")
+}
+
+// infoTimeout bounds how long printModuleInfo waits for the go command, which
+// may need to reach the module proxy.
+const infoTimeout = 30 * time.Second
+
+// printModuleInfo queries the go command for the module at pkg and prints its
+// path, version and replacement. If pkg has no version, the latest version is
+// queried.
+func printModuleInfo(pkg string) error {
+	if !strings.Contains(pkg, "@") {
+		pkg += "@latest"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), infoTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, goBin(), "list", "-m", "-json", pkg)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("timed out querying %s after %s (are you offline?)", pkg, infoTimeout)
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if isNetworkError(msg) {
+			return fmt.Errorf("could not reach the module proxy for %s (are you offline?): %s", pkg, msg)
+		}
+		if msg != "" {
+			return fmt.Errorf("go list %s: %s", pkg, msg)
+		}
+		return fmt.Errorf("go list %s: %w", pkg, err)
+	}
+
+	var mod struct {
+		Path    string
+		Version string
+		Time    *time.Time
+		Replace *struct {
+			Path    string
+			Version string
+		}
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &mod); err != nil {
+		return fmt.Errorf("decode module info: %w", err)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "path\t%s\n", mod.Path)
+	fmt.Fprintf(tw, "version\t%s\n", mod.Version)
+	if mod.Time != nil {
+		fmt.Fprintf(tw, "time\t%s\n", mod.Time.Format(time.RFC3339))
+	}
+	if mod.Replace != nil {
+		fmt.Fprintf(tw, "replaced\t%s\n", strings.TrimSpace(mod.Replace.Path+" "+mod.Replace.Version))
+	} else {
+		fmt.Fprintln(tw, "replaced\tno")
+	}
+	return tw.Flush()
+}
+
+// isNetworkError reports whether msg, the output of a failed go command, looks
+// like it was caused by a network failure.
+func isNetworkError(msg string) bool {
+	for _, s := range []string{
+		"dial tcp",
+		"no such host",
+		"network is unreachable",
+		"connection refused",
+		"i/o timeout",
+		"TLS handshake timeout",
+	} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// shouldConfirm reports whether a destructive command should ask for
+// confirmation, as configured by confirmDestructive, unless yes, set by
+// --yes, skips it. It returns an error if confirmation is required but can't
+// be asked for.
+func shouldConfirm(yes bool) (bool, error) {
+	if yes || cfg.ConfirmDestructive == confirmNever {
+		return false, nil
+	}
+	interactive := term.IsTerminal(int(os.Stdin.Fd()))
+	if cfg.ConfirmDestructive == confirmAlways && !interactive {
+		return false, errors.New("confirmation is required, but standard input is not a terminal; pass --yes to go ahead")
+	}
+	return interactive, nil
+}
+
+// confirmDestructive asks question before a destructive change, if
+// shouldConfirm says to, and reports whether the change may go ahead.
+func confirmDestructive(question string, yes bool) (bool, error) {
+	ask, err := shouldConfirm(yes)
+	if err != nil || !ask {
+		return err == nil, err
+	}
+	return confirm(question), nil
+}
+
+// confirm asks the user a yes/no question on standard input, and reports
+// whether they answered yes.
+func confirm(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
 }
 
 var configDir string
 
+// profile is the name of the active alias profile. The empty string selects
+// the default profile.
+var profile string
+
+// offline is set by the --offline flag or the AGO_OFFLINE environment variable.
+// When set, ago never reaches the network itself, and the go command is limited
+// to the module cache.
+var offline bool
+
+// goEnvExtra holds variables, of the form name=value, added to the environment
+// of the go commands ago runs.
+var goEnvExtra []string
+
+// forceExpand is set by the --expand flag. When set, the arguments of commands
+// ago doesn't otherwise handle are expanded too.
+var forceExpand bool
+
+// noExpand is set by the --no-expand flag or the AGO_NO_EXPAND environment
+// variable. When set, commands that run go are passed to it untouched.
+var noExpand bool
+
 func init() {
+	profile = os.Getenv("AGO_PROFILE")
+	offline = envBool("AGO_OFFLINE")
+	noExpand = envBool("AGO_NO_EXPAND")
+	traceEnabled = envBool("AGO_TRACE")
+
 	if configDir = os.Getenv("AGO_CONFIG_DIR"); configDir != "" {
 		return
 	}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// The settings are otherwise loaded by main; tests start from the
+	// defaults a missing settings file gives.
+	cfg.Format = formatJSON
+	os.Exit(m.Run())
+}
+
+func TestVersionConflicts(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []versionConflict
+	}{
+		{[]string{"ago", "get", "github.com/foo/bar", "github.com/baz/qux@v1.0.0"}, nil},
+		{[]string{"ago", "get", "github.com/foo/bar@v1.2.0", "github.com/foo/bar@v1.3.0"}, []versionConflict{
+			{Package: "github.com/foo/bar", Args: []string{"github.com/foo/bar@v1.2.0", "github.com/foo/bar@v1.3.0"}},
+		}},
+		{[]string{"ago", "get", "github.com/foo/bar", "github.com/foo/bar/v3@v3.0.0"}, []versionConflict{
+			{Package: "github.com/foo/bar", Args: []string{"github.com/foo/bar", "github.com/foo/bar/v3@v3.0.0"}},
+		}},
+		{[]string{"ago", "install", "github.com/foo/bar@latest", "github.com/foo/bar"}, []versionConflict{
+			{Package: "github.com/foo/bar", Args: []string{"github.com/foo/bar@latest", "github.com/foo/bar"}},
+		}},
+	}
+	for _, tt := range tests {
+		if got := versionConflicts(tt.args); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("versionConflicts(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
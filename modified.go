@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// modifiedPath returns the path of the file recording when each alias of the
+// aliases file at path was last added or changed. It is kept beside the
+// aliases file, so that each profile has its own, but named so that it is
+// never mistaken for the aliases file of a profile.
+func modifiedPath(path string) string {
+	return filepath.Join(filepath.Dir(path), "modified-"+filepath.Base(path))
+}
+
+// loadModified returns when each alias of the aliases file at path was last
+// added or changed. Aliases that haven't been since the times were first
+// recorded, or that were only changed by editing the file, have no time.
+func loadModified(path string) (map[string]time.Time, error) {
+	data, err := os.ReadFile(modifiedPath(path))
+	if os.IsNotExist(err) {
+		return map[string]time.Time{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read modification times: %w", err)
+	}
+	modified := make(map[string]time.Time)
+	if err := json.Unmarshal(data, &modified); err != nil {
+		return nil, fmt.Errorf("decode modification times: %w", err)
+	}
+	return modified, nil
+}
+
+// recordModified records the aliases that differ between old and aliases,
+// the contents of the aliases file at path before and after a change, as
+// modified now, and forgets those that were removed.
+func recordModified(path string, old, aliases map[string]aliasEntry) error {
+	modified, err := loadModified(path)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	for name, entry := range aliases {
+		if prev, ok := old[name]; !ok || !sameEntry(prev, entry) {
+			modified[name] = now
+		}
+	}
+	for name := range modified {
+		if _, ok := aliases[name]; !ok {
+			delete(modified, name)
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(modified); err != nil {
+		return fmt.Errorf("encode modification times: %w", err)
+	}
+	if err := writeFileAtomic(modifiedPath(path), buf.Bytes()); err != nil {
+		return fmt.Errorf("write modification times: %w", err)
+	}
+	return nil
+}
+
+// sameEntry reports whether a and b are stored the same way in the aliases
+// file.
+func sameEntry(a, b aliasEntry) bool {
+	x, errX := json.Marshal(a)
+	y, errY := json.Marshal(b)
+	return errX == nil && errY == nil && bytes.Equal(x, y)
+}
+
+// modifiedSince returns the aliases added or changed within since of now,
+// according to modified. Aliases with no recorded time are left out.
+func modifiedSince(aliases map[string]aliasEntry, modified map[string]time.Time, since time.Duration) map[string]aliasEntry {
+	recent := make(map[string]aliasEntry)
+	for name, entry := range aliases {
+		if t, ok := modified[name]; ok && time.Since(t) <= since {
+			recent[name] = entry
+		}
+	}
+	return recent
+}
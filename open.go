@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// repoHosts are the code hosts whose module paths begin with the path of a
+// repository, host/owner/repo, that is also the path of its web page.
+var repoHosts = []string{"github.com", "gitlab.com", "bitbucket.org", "codeberg.org"}
+
+// repoURL returns the URL of the web page of the repository holding the
+// package pkg, which may have a version.
+func repoURL(pkg string) (string, error) {
+	pkg, _, _ = strings.Cut(pkg, "@")
+	pkg = strings.TrimSuffix(pkg, "/...")
+	pkg, _ = splitMajor(pkg)
+	elems := strings.Split(pkg, "/")
+	for _, host := range repoHosts {
+		if elems[0] != host {
+			continue
+		}
+		if len(elems) < 3 {
+			return "", fmt.Errorf("%s does not name a repository on %s", pkg, host)
+		}
+		return "https://" + strings.Join(elems[:3], "/"), nil
+	}
+	return "", fmt.Errorf("don't know the web page of %s; only %s are supported", pkg, strings.Join(repoHosts, ", "))
+}
+
+// openBrowser opens url in the default web browser.
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		// The empty argument is the title of the window start would
+		// otherwise take the quoted URL for.
+		cmd = exec.Command("cmd", "/c", "start", "", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("open %s: %w", url, err)
+	}
+	return nil
+}
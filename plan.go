@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// argPackages returns the packages among the arguments following the command
+// in args, leaving out flags and their values.
+func argPackages(args []string) []string {
+	var pkgs []string
+	var isValue bool
+	for _, arg := range args[2:] {
+		switch {
+		case endsPackages(args[1], arg):
+			return pkgs
+		case isValue:
+			isValue = false
+		case takesValue(arg):
+			isValue = true
+		case !strings.HasPrefix(arg, "-"):
+			pkgs = append(pkgs, arg)
+		}
+	}
+	return pkgs
+}
+
+// printPlan writes the numbered list of packages that ago get or ago install,
+// cmd, is about to fetch, as it does before fetching a bundle or the packages
+// of a file.
+func printPlan(w io.Writer, cmd string, pkgs []string) {
+	noun := "packages"
+	if len(pkgs) == 1 {
+		noun = "package"
+	}
+	fmt.Fprintf(w, "ago will %s %d %s:\n", cmd, len(pkgs), noun)
+	width := len(fmt.Sprint(len(pkgs)))
+	for i, pkg := range pkgs {
+		fmt.Fprintf(w, "  %*d. %s\n", width, i+1, pkg)
+	}
+}
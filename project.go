@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const projectFile = ".ago.json"
+
+// projectConfig is the configuration of a project, read from a .ago.json file
+// in the project's root directory. It applies to ago commands run anywhere in
+// the project tree, and takes precedence over the environment and config.json.
+type projectConfig struct {
+	// GoBin is the go command to run. A relative path containing a
+	// separator is resolved against the project directory.
+	GoBin string `json:"goBin,omitempty"`
+	// ExtraArgs replaces the extraArgs setting of config.json.
+	ExtraArgs []string `json:"extraArgs,omitempty"`
+	// Aliases are used in addition to the aliases file and AGO_ALIASES,
+	// taking precedence over both.
+	Aliases map[string]aliasEntry `json:"aliases,omitempty"`
+}
+
+// projectMaxDepth returns how many parents of the working directory are
+// searched for a .ago.json file, set by AGO_PROJECT_MAX_DEPTH, or -1 if the
+// search is unlimited, the default.
+func projectMaxDepth() (int, error) {
+	s := os.Getenv("AGO_PROJECT_MAX_DEPTH")
+	if s == "" {
+		return -1, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid AGO_PROJECT_MAX_DEPTH %q: want a number of directories, 0 or more", s)
+	}
+	return n, nil
+}
+
+// findProjectFile returns the path of the .ago.json file in the working
+// directory or the nearest of its parents, or "" if there is none. The search
+// goes at most AGO_PROJECT_MAX_DEPTH parents up and, if AGO_PROJECT_STOP_AT_GIT
+// is set, no further than the root of the git repository, the first directory
+// containing .git.
+func findProjectFile() (string, error) {
+	maxDepth, err := projectMaxDepth()
+	if err != nil {
+		return "", err
+	}
+	stopAtGit := envBool("AGO_PROJECT_STOP_AT_GIT")
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("find project config: %w", err)
+	}
+	for depth := 0; ; depth++ {
+		path := filepath.Join(dir, projectFile)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+		if depth == maxDepth {
+			return "", nil
+		}
+		if stopAtGit {
+			// .git is a file in worktrees and submodules.
+			if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+				return "", nil
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// loadProject reads the project config that applies to the working directory.
+// Outside of a project, the config is empty.
+func loadProject() (projectConfig, error) {
+	var p projectConfig
+	path, err := findProjectFile()
+	if err != nil || path == "" {
+		return p, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return p, fmt.Errorf("read project config: %w", err)
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return p, fmt.Errorf("decode project config %s: %w", path, err)
+	}
+	if strings.ContainsRune(p.GoBin, filepath.Separator) && !filepath.IsAbs(p.GoBin) {
+		p.GoBin = filepath.Join(filepath.Dir(path), p.GoBin)
+	}
+	return p, nil
+}
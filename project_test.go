@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindProjectFile(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{"repo/.git", "repo/a/b", "repo/sub/c", "wt/d"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A worktree has a .git file rather than a directory.
+	for _, file := range []string{projectFile, "repo/sub/" + projectFile, "wt/.git"} {
+		if err := os.WriteFile(filepath.Join(root, file), []byte("{}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	rootFile := projectFile
+	subFile := filepath.Join("repo", "sub", projectFile)
+	tests := []struct {
+		dir       string
+		maxDepth  string
+		stopAtGit string
+		want      string
+	}{
+		{".", "", "", rootFile},
+		{".", "0", "", rootFile},
+		{"repo/a/b", "", "", rootFile},
+		{"repo/a/b", "2", "", ""},
+		{"repo/a/b", "3", "", rootFile},
+		{"repo/a/b", "", "1", ""},
+		{"repo/sub/c", "", "", subFile},
+		{"repo/sub/c", "0", "", ""},
+		{"repo/sub/c", "1", "1", subFile},
+		{"repo/sub", "", "1", subFile},
+		{"wt/d", "", "", rootFile},
+		{"wt/d", "", "1", ""},
+		{"wt/d", "1", "", ""},
+	}
+	for _, tt := range tests {
+		t.Setenv("AGO_PROJECT_MAX_DEPTH", tt.maxDepth)
+		t.Setenv("AGO_PROJECT_STOP_AT_GIT", tt.stopAtGit)
+		if err := os.Chdir(filepath.Join(root, tt.dir)); err != nil {
+			t.Fatal(err)
+		}
+		want := tt.want
+		if want != "" {
+			want = filepath.Join(root, want)
+		}
+		got, err := findProjectFile()
+		if err != nil {
+			t.Errorf("findProjectFile in %s (max depth %q, stop at git %q) failed: %v", tt.dir, tt.maxDepth, tt.stopAtGit, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("findProjectFile in %s (max depth %q, stop at git %q) = %q, want %q", tt.dir, tt.maxDepth, tt.stopAtGit, got, want)
+		}
+	}
+
+	for _, s := range []string{"-1", "two"} {
+		t.Setenv("AGO_PROJECT_MAX_DEPTH", s)
+		if _, err := findProjectFile(); err == nil {
+			t.Errorf("findProjectFile with AGO_PROJECT_MAX_DEPTH=%s succeeded, want an error", s)
+		}
+	}
+}
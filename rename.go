@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// aliasRename is the renaming of an alias.
+type aliasRename struct {
+	From, To string
+}
+
+// planRenames returns the renames of the aliases whose names match re, with
+// each match replaced by repl as in regexp.ReplaceAllString, sorted by their
+// old names. It fails without renaming anything if a new name is empty, or
+// would collide with another alias or another new name.
+func planRenames(aliases map[string]aliasEntry, re *regexp.Regexp, repl string) ([]aliasRename, error) {
+	var renames []aliasRename
+	for _, name := range sortedNames(aliases) {
+		if to := re.ReplaceAllString(name, repl); to != name {
+			renames = append(renames, aliasRename{From: name, To: to})
+		}
+	}
+	return renames, checkRenames(aliases, renames)
+}
+
+// parseKeyTransform parses the --transform flag of ago alias import, which
+// renames the imported aliases, into a pattern and replacement for
+// planRenames. It is either prefix:P, which adds P to the start of every name,
+// or regexp:PATTERN=REPLACEMENT, which replaces each match of PATTERN as ago
+// alias rename --regex does. The replacement follows the last equals sign.
+func parseKeyTransform(spec string) (*regexp.Regexp, string, error) {
+	kind, arg, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "prefix":
+		if arg == "" {
+			return nil, "", fmt.Errorf("invalid transform %q: empty prefix", spec)
+		}
+		return regexp.MustCompile("^"), strings.ReplaceAll(arg, "$", "$$"), nil
+	case "regexp":
+		idx := strings.LastIndex(arg, "=")
+		if idx == -1 {
+			return nil, "", fmt.Errorf("invalid transform %q: want regexp:PATTERN=REPLACEMENT", spec)
+		}
+		re, err := regexp.Compile(arg[:idx])
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid transform %q: %w", spec, err)
+		}
+		return re, arg[idx+1:], nil
+	}
+	return nil, "", fmt.Errorf("invalid transform %q: want prefix:P or regexp:PATTERN=REPLACEMENT", spec)
+}
+
+// checkRenames returns an error if applying renames to aliases would leave an
+// alias with an empty name or lose an alias to a collision.
+func checkRenames(aliases map[string]aliasEntry, renames []aliasRename) error {
+	renamed := make(map[string]bool, len(renames))
+	for _, r := range renames {
+		renamed[r.From] = true
+	}
+	taken := make(map[string]string, len(renames))
+	for _, r := range renames {
+		if strings.TrimSpace(r.To) == "" {
+			return fmt.Errorf("renaming %q would leave it with an empty name", r.From)
+		}
+		if _, ok := aliases[r.To]; ok && !renamed[r.To] {
+			return fmt.Errorf("cannot rename %q to %q: alias %q already exists", r.From, r.To, r.To)
+		}
+		if other, ok := taken[r.To]; ok {
+			return fmt.Errorf("cannot rename both %q and %q to %q", other, r.From, r.To)
+		}
+		taken[r.To] = r.From
+	}
+	return nil
+}
+
+// applyRenames renames aliases in place. The renames must have been checked
+// with checkRenames.
+func applyRenames(aliases map[string]aliasEntry, renames []aliasRename) {
+	entries := make(map[string]aliasEntry, len(renames))
+	for _, r := range renames {
+		entries[r.To] = aliases[r.From]
+		delete(aliases, r.From)
+	}
+	for name, entry := range entries {
+		aliases[name] = entry
+	}
+}
+
+// updateRefs rewrites the packages of aliases that are chained to an alias
+// being renamed, so that they refer to its new name. It must be called before
+// applyRenames, since references are matched as they are expanded, against the
+// old names. It returns the names of the aliases it changed, sorted.
+func updateRefs(aliases map[string]aliasEntry, renames []aliasRename) []string {
+	to := make(map[string]string, len(renames))
+	for _, r := range renames {
+		to[r.From] = r.To
+	}
+	retarget := func(pkg string) (string, bool) {
+		ref, _ := matchAlias(aliases, pkg, true)
+		if name, ok := to[ref]; ok {
+			return name + pkg[len(ref):], true
+		}
+		return pkg, false
+	}
+	updated := make(map[string]aliasEntry)
+	for name, entry := range aliases {
+		var changed bool
+		if entry.isBundle() {
+			bundle := make([]string, len(entry.Bundle))
+			for i, pkg := range entry.Bundle {
+				var ok bool
+				bundle[i], ok = retarget(pkg)
+				changed = changed || ok
+			}
+			entry.Bundle = bundle
+		} else {
+			entry.Package, changed = retarget(entry.Package)
+		}
+		if changed {
+			updated[name] = entry
+		}
+	}
+	for name, entry := range updated {
+		aliases[name] = entry
+	}
+	return sortedNames(updated)
+}
+
+// brokenRefs returns the names of the aliases in layer, such as those of a
+// project file, that are chained to an alias being renamed in another layer,
+// and so will no longer expand as they did. References to aliases that layer
+// defines itself are not broken. Like updateRefs, it must be called before
+// applyRenames.
+func brokenRefs(layer, renamedIn map[string]aliasEntry, renames []aliasRename) []string {
+	from := make(map[string]bool, len(renames))
+	for _, r := range renames {
+		if _, ok := layer[r.From]; !ok {
+			from[r.From] = true
+		}
+	}
+	merged := mergeAliases(renamedIn, layer)
+	var broken []string
+	for _, name := range sortedNames(layer) {
+		entry := layer[name]
+		pkgs := entry.Bundle
+		if !entry.isBundle() {
+			pkgs = []string{entry.Package}
+		}
+		for _, pkg := range pkgs {
+			if ref, _ := matchAlias(merged, pkg, true); from[ref] {
+				broken = append(broken, name)
+				break
+			}
+		}
+	}
+	return broken
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// maxRetryDelay caps the delay between retries of a failed go command.
+const maxRetryDelay = 30 * time.Second
+
+// isTransientError reports whether msg, the output of a failed go command,
+// looks like a network failure that could pass if the command were run again,
+// including errors from an overloaded proxy.
+func isTransientError(msg string) bool {
+	return isNetworkError(msg) || containsAny(msg,
+		"connection reset by peer",
+		"502 Bad Gateway",
+		"503 Service Unavailable",
+		"504 Gateway Timeout",
+	)
+}
+
+// retryDelay returns how long to wait before retry number n, counting from 0:
+// a second, doubling with each retry up to maxRetryDelay.
+func retryDelay(n int) time.Duration {
+	d := time.Second << n
+	if d <= 0 || d > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return d
+}
+
+// runGoWithRetries returns a function that runs the go command like
+// runGoCapture, running it again up to retries times, with backoff, while it
+// fails with what looks like a transient network error. The error of the last
+// run is returned. If hints is set, a troubleshooting note is printed when the
+// command finally fails, like runGoWithHints.
+func runGoWithRetries(retries int, hints bool) func(args ...string) error {
+	return func(args ...string) error {
+		for n := 0; ; n++ {
+			out, err := runGoCapture(args...)
+			if err == nil {
+				return nil
+			}
+			msg := out.Stderr.String()
+			if n >= retries || !isTransientError(msg) {
+				if hints {
+					if hint := networkHint(msg); hint != "" {
+						fmt.Fprint(os.Stderr, hint)
+					}
+				}
+				return err
+			}
+			delay := retryDelay(n)
+			fmt.Fprintf(os.Stderr, "ago: go failed with a network error; retrying in %v (%d of %d)\n", delay, n+1, retries)
+			time.Sleep(delay)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// runAlias runs the binary of the aliased package named by the first of args,
+// installing it first if it isn't already installed. The remaining args,
+// after an optional "--" separator, are passed to the binary. If the binary
+// exits with a non-zero status, the returned error is an *exec.ExitError.
+func runAlias(aliases map[string]aliasEntry, args []string, noInstall bool) error {
+	if len(args) == 0 {
+		return errors.New("not enough arguments")
+	}
+	res, err := resolvePackage(aliases, args[0])
+	if err != nil {
+		return err
+	}
+	pkg := res.Result
+	// Usage only helps later alias commands, so failing to record it
+	// shouldn't fail the tool.
+	_ = recordUsage(res.Chain)
+	progArgs := args[1:]
+	if len(progArgs) > 0 && progArgs[0] == "--" {
+		progArgs = progArgs[1:]
+	}
+
+	name := binaryName(pkg)
+	if name == "" {
+		return fmt.Errorf("cannot determine the binary name of %q", pkg)
+	}
+	binDir, err := goBinDir()
+	if err != nil {
+		return err
+	}
+	bin := filepath.Join(binDir, name+exeSuffix())
+
+	if _, err := os.Stat(bin); os.IsNotExist(err) {
+		if noInstall {
+			return fmt.Errorf("%s is not installed; install it with: ago install %s", name, args[0])
+		}
+		// go install needs a version when run outside of a module.
+		if !strings.Contains(pkg, "@") {
+			pkg += "@latest"
+		}
+		fmt.Fprintf(os.Stderr, "> go install %s\n", pkg)
+		// Standard output belongs to the binary, so go's goes to
+		// standard error.
+		cmd := goCommand("install", pkg)
+		cmd.Stdout = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("go install %s: %w", pkg, err)
+		}
+	} else if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(bin, progArgs...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
+
+// binaryName returns the name of the binary go install builds for pkg: the
+// last element of its path, skipping a major version suffix.
+func binaryName(pkg string) string {
+	if idx := strings.LastIndex(pkg, "@"); idx != -1 {
+		pkg = pkg[:idx]
+	}
+	pkg = strings.TrimRight(pkg, "/")
+	name := path.Base(pkg)
+	if len(name) > 1 && name[0] == 'v' {
+		if _, err := strconv.Atoi(name[1:]); err == nil {
+			name = path.Base(path.Dir(pkg))
+		}
+	}
+	if name == "." || name == "/" {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Sources of an alias, in increasing order of precedence.
+const (
+	sourceGlobal  = "global"
+	sourceEnv     = "env"
+	sourceProject = "project"
+	sourceInline  = "inline"
+)
+
+// aliasLayers holds the aliases of each source separately, to report where an
+// alias was defined.
+type aliasLayers struct {
+	Global  map[string]aliasEntry
+	Env     map[string]aliasEntry
+	Project map[string]aliasEntry
+	// Inline holds the aliases given with --alias.
+	Inline map[string]aliasEntry
+}
+
+// source returns the source the alias name is taken from, or "" if no source
+// defines it.
+func (l aliasLayers) source(name string) string {
+	if _, ok := l.Inline[name]; ok {
+		return sourceInline
+	}
+	if _, ok := l.Project[name]; ok {
+		return sourceProject
+	}
+	if _, ok := l.Env[name]; ok {
+		return sourceEnv
+	}
+	if _, ok := l.Global[name]; ok {
+		return sourceGlobal
+	}
+	return ""
+}
+
+// merged returns the aliases of every source, merged by precedence.
+func (l aliasLayers) merged() map[string]aliasEntry {
+	return mergeAliases(l.Global, l.Env, l.Project, l.Inline)
+}
+
+// shadowed returns the sources of lower precedence than the winning one that
+// also define the alias name, highest first.
+func (l aliasLayers) shadowed(name string) []string {
+	var sources []string
+	for _, s := range []struct {
+		name    string
+		aliases map[string]aliasEntry
+	}{{sourceProject, l.Project}, {sourceEnv, l.Env}, {sourceGlobal, l.Global}} {
+		if _, ok := s.aliases[name]; ok && s.name != l.source(name) {
+			sources = append(sources, s.name)
+		}
+	}
+	return sources
+}
+
+// effectiveAlias is an alias of the merged aliases, as printed by
+// printEffective.
+type effectiveAlias struct {
+	Package  string   `json:"package"`
+	Source   string   `json:"source"`
+	Shadowed []string `json:"overrides,omitempty"`
+}
+
+// printEffective prints the merged aliases of layers, sorted by name, with
+// the source each is taken from and any sources it overrides.
+func printEffective(layers aliasLayers, asJSON bool) error {
+	merged := layers.merged()
+	if asJSON {
+		effective := make(map[string]effectiveAlias, len(merged))
+		for name, entry := range merged {
+			effective[name] = effectiveAlias{Package: entry.target(), Source: layers.source(name), Shadowed: layers.shadowed(name)}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(effective)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ALIAS\tPACKAGE\tSOURCE\tOVERRIDES")
+	fmt.Fprintln(tw, "-----\t-------\t------\t---------")
+	for _, name := range sortedNames(merged) {
+		overrides := strings.Join(layers.shadowed(name), ",")
+		if overrides == "" {
+			overrides = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", name, merged[name].target(), layers.source(name), overrides)
+	}
+	return tw.Flush()
+}
+
+// whichResult is the result of ago which --json for a single argument.
+type whichResult struct {
+	Arg    string `json:"arg"`
+	Result string `json:"result,omitempty"`
+	// Alias is the name of the alias that matched Arg, and Source where that
+	// alias was defined.
+	Alias  string `json:"alias,omitempty"`
+	Source string `json:"source,omitempty"`
+	// Network reports whether the network was used to expand Arg. Expanding
+	// only ever reads the aliases, so it is always false today, but lets
+	// tools rely on the field if that changes.
+	Network bool `json:"network"`
+	// Elapsed is how long expanding Arg took.
+	Elapsed time.Duration `json:"elapsedNs"`
+	Error   string        `json:"error,omitempty"`
+	// chain lists the aliases used to expand Arg, to count their use.
+	chain []string
+}
+
+// which expands arg like expandArg, describing the expansion.
+func which(aliases map[string]aliasEntry, layers aliasLayers, arg string) whichResult {
+	start := time.Now()
+	r := whichResult{Arg: arg}
+	res, err := resolvePackage(aliases, arg)
+	r.Elapsed = time.Since(start)
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	r.Result = res.Result
+	r.chain = res.Chain
+	if res.Matched {
+		r.Alias = res.Alias
+		r.Source = layers.source(res.Alias)
+	}
+	return r
+}
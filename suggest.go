@@ -0,0 +1,76 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// suggestAlias returns the name of the alias closest to arg, for use in a
+// "did you mean" hint when arg matched no alias. It returns the empty string
+// if no alias is close enough to be worth suggesting.
+func suggestAlias(aliases map[string]aliasEntry, arg string) string {
+	if strings.HasPrefix(arg, "-") || strings.HasPrefix(arg, ".") {
+		return ""
+	}
+	// Only the part of the argument that would have matched an alias is
+	// compared, not any subpath or version.
+	name := arg
+	if idx := strings.IndexAny(name, "/@"); idx != -1 {
+		name = name[:idx]
+	}
+	if name == "" {
+		return ""
+	}
+
+	// Allow one edit for short names, and two for longer ones. Anything
+	// more suggests unrelated names.
+	threshold := 1
+	if len(name) > 4 {
+		threshold = 2
+	}
+
+	names := make([]string, 0, len(aliases))
+	for alias := range aliases {
+		names = append(names, alias)
+	}
+	sort.Strings(names)
+
+	var best string
+	bestDist := threshold + 1
+	for _, alias := range names {
+		if d := levenshtein(name, alias); d > 0 && d < bestDist {
+			best = alias
+			bestDist = d
+		}
+	}
+	return best
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, minInt(curr[j-1]+1, prev[j-1]+cost))
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
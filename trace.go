@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// traceEnabled is set by the --trace flag or the AGO_TRACE environment
+// variable.
+var traceEnabled bool
+
+// tracePrefix begins every trace line, so that traces can be picked out of
+// the go command's output with grep.
+const tracePrefix = "ago-trace: "
+
+// tracef writes a line describing a step of alias expansion to standard
+// error, if tracing is enabled.
+func tracef(format string, args ...interface{}) {
+	if traceEnabled {
+		fmt.Fprintf(os.Stderr, tracePrefix+format+"\n", args...)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/mod/semver"
+)
+
+// updateWorkers bounds the number of module proxy queries checkUpdates makes
+// at once.
+const updateWorkers = 4
+
+// updateTimeout bounds how long checkUpdates waits for the module proxy in
+// all. Checking an alias may take several queries, so a per-query timeout
+// would let a slow proxy stall the check for minutes.
+const updateTimeout = 30 * time.Second
+
+// updateCheck is the result of checking an alias with a pinned version for a
+// newer version.
+type updateCheck struct {
+	Alias   string
+	Module  string
+	Current string
+	Latest  string
+	Err     error
+}
+
+// checkUpdates checks every alias with a pinned version for a newer version
+// of its module, querying the module proxy with a bounded pool of workers.
+// Failures are recorded in the result for the alias rather than returned, so
+// that one unreachable module doesn't spoil the whole report. Aliases that
+// aren't checked within updateTimeout fail as unreachable.
+func checkUpdates(aliases map[string]aliasEntry) []updateCheck {
+	var checks []updateCheck
+	for _, name := range sortedNames(aliases) {
+		entry := aliases[name]
+		if entry.Version == "" || entry.isBundle() || isPathAlias(entry.Package) || hasScheme(entry.Package) {
+			continue
+		}
+		checks = append(checks, updateCheck{Alias: name, Current: entry.Version})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), updateTimeout)
+	defer cancel()
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, updateWorkers)
+	for i := range checks {
+		wg.Add(1)
+		go func(c *updateCheck) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			c.Module, c.Latest, c.Err = latestVersion(ctx, aliases[c.Alias].Package)
+		}(&checks[i])
+	}
+	wg.Wait()
+	return checks
+}
+
+// latestVersion returns the path and latest version of the module providing
+// pkg. Since pkg may be a package within a module, the path is shortened one
+// element at a time until the module proxy knows it, or ctx is done.
+func latestVersion(ctx context.Context, pkg string) (module, version string, err error) {
+	if err := requireNetwork("alias check-updates"); err != nil {
+		return "", "", err
+	}
+	pkg = strings.TrimSuffix(pkg, "/...")
+	var firstErr error
+	for path := pkg; ; {
+		version, err := queryLatest(ctx, path)
+		if err == nil {
+			return path, version, nil
+		}
+		if errors.Is(err, errUnreachable) {
+			return "", "", err
+		}
+		// The error for the full path is the most useful one.
+		if firstErr == nil {
+			firstErr = err
+		}
+		idx := strings.LastIndex(path, "/")
+		if idx == -1 {
+			return "", "", firstErr
+		}
+		path = path[:idx]
+	}
+}
+
+// errUnreachable is returned by queryLatest when the module proxy can't be
+// reached.
+var errUnreachable = errors.New("could not reach the module proxy (are you offline?)")
+
+// queryLatest returns the latest version of the module at path.
+func queryLatest(ctx context.Context, path string) (string, error) {
+	if ctx.Err() != nil {
+		return "", errUnreachable
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, goBin(), "list", "-m", "-json", path+"@latest")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) || isNetworkError(stderr.String()) {
+			return "", errUnreachable
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", errors.New(msg)
+		}
+		return "", err
+	}
+	var mod struct{ Version string }
+	if err := json.Unmarshal(stdout.Bytes(), &mod); err != nil {
+		return "", fmt.Errorf("decode module info: %w", err)
+	}
+	return mod.Version, nil
+}
+
+// printUpdates prints the results of checkUpdates as a table.
+func printUpdates(checks []updateCheck) error {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ALIAS\tMODULE\tCURRENT\tLATEST\tSTATUS")
+	fmt.Fprintln(tw, "-----\t------\t-------\t------\t------")
+	for _, c := range checks {
+		module, latest, status := c.Module, c.Latest, "up to date"
+		switch {
+		case c.Err != nil:
+			module, latest = "-", "-"
+			status = "error: " + c.Err.Error()
+		case !semver.IsValid(c.Current):
+			status = "pinned to a non-semantic version"
+		case semver.Compare(c.Latest, c.Current) > 0:
+			status = "update available"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", c.Alias, module, c.Current, latest, status)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const usageFile = "usage.json"
+
+// usageRecord records how often an alias has been expanded by any command,
+// and when it last was.
+type usageRecord struct {
+	Count    int       `json:"count"`
+	LastUsed time.Time `json:"lastUsed"`
+}
+
+// loadUsage returns the recorded usage of each alias.
+func loadUsage() (map[string]usageRecord, error) {
+	data, err := os.ReadFile(profilePath(usageFile))
+	if os.IsNotExist(err) {
+		return map[string]usageRecord{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read usage stats: %w", err)
+	}
+	usage := make(map[string]usageRecord)
+	if err := json.Unmarshal(data, &usage); err != nil {
+		return nil, fmt.Errorf("decode usage stats: %w", err)
+	}
+	return usage, nil
+}
+
+// recordUsage counts a use of each of the aliases names.
+func recordUsage(names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	usage, err := loadUsage()
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, name := range names {
+		rec := usage[name]
+		rec.Count++
+		rec.LastUsed = now
+		usage[name] = rec
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(usage); err != nil {
+		return fmt.Errorf("encode usage stats: %w", err)
+	}
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return fmt.Errorf("create config dir: %w", err)
+	}
+	if err := writeFileAtomic(profilePath(usageFile), buf.Bytes()); err != nil {
+		return fmt.Errorf("write usage stats: %w", err)
+	}
+	return nil
+}
+
+// unusedAliases returns the aliases that have never been used, or, if since
+// is positive, not within since of now.
+func unusedAliases(aliases map[string]aliasEntry, usage map[string]usageRecord, since time.Duration) map[string]aliasEntry {
+	unused := make(map[string]aliasEntry)
+	for name, entry := range aliases {
+		rec, ok := usage[name]
+		if !ok || rec.Count == 0 || (since > 0 && time.Since(rec.LastUsed) > since) {
+			unused[name] = entry
+		}
+	}
+	return unused
+}
+
+// parseSince parses the --since flag, a duration such as 12h or a number of
+// days such as 30d.
+func parseSince(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid duration %q; want e.g. 30d or 12h", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q; want e.g. 30d or 12h", s)
+	}
+	return d, nil
+}
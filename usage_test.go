@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestUsageOfRunAlias(t *testing.T) {
+	savedDir, savedProfile, savedBinDir := configDir, profile, binDirCache
+	t.Cleanup(func() { configDir, profile, binDirCache = savedDir, savedProfile, savedBinDir })
+	configDir, profile = t.TempDir(), ""
+	binDirCache.done, binDirCache.dir, binDirCache.err = true, t.TempDir(), nil
+
+	aliases := map[string]aliasEntry{
+		"xt":  {Package: "github.com/foo/xt"},
+		"old": {Package: "github.com/foo/old"},
+	}
+	// The binary isn't installed, so runAlias fails without running it, but
+	// the alias was still used.
+	if err := runAlias(aliases, []string{"xt", "hi"}, true); err == nil {
+		t.Fatal("runAlias of a binary that isn't installed succeeded")
+	}
+	usage, err := loadUsage()
+	if err != nil {
+		t.Fatalf("loadUsage failed: %v", err)
+	}
+	if got := usage["xt"].Count; got != 1 {
+		t.Errorf("usage of xt = %d, want 1", got)
+	}
+	unused := unusedAliases(aliases, usage, 0)
+	if _, ok := unused["xt"]; ok {
+		t.Error("alias xt used by ago x is unused")
+	}
+	if _, ok := unused["old"]; !ok {
+		t.Error("alias old is not unused")
+	}
+}
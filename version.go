@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// modulePath is the module path ago is installed from by self-update.
+const modulePath = "github.com/deitrix/ago"
+
+// buildVersion returns the module version the running binary was built from.
+func buildVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Version == "" {
+		return "(unknown)"
+	}
+	return info.Main.Version
+}
+
+// selfUpdate installs the latest version of ago with go install and reports
+// the versions before and after.
+func selfUpdate() error {
+	if err := requireNetwork("self-update"); err != nil {
+		return err
+	}
+	if _, err := exec.LookPath(goBin()); err != nil {
+		return errors.New("self-update requires the go command, which was not found in PATH")
+	}
+
+	before := buildVersion()
+
+	fmt.Printf("> go install %s@latest\n", modulePath)
+	cmd := exec.Command(goBin(), "install", modulePath+"@latest")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("go install: %w", err)
+	}
+
+	binDir, err := goBinDir()
+	if err != nil {
+		return err
+	}
+	installed := filepath.Join(binDir, "ago"+exeSuffix())
+	after, err := binaryVersion(installed)
+	if err != nil {
+		return err
+	}
+
+	if before == after {
+		fmt.Printf("ago is up to date (%s)\n", after)
+	} else {
+		fmt.Printf("updated ago from %s to %s\n", before, after)
+	}
+
+	// go install only ever writes to the bin directory. If ago is running
+	// from somewhere else, such as a read-only system path, it is not
+	// replaced.
+	if exe, err := os.Executable(); err == nil {
+		if !sameFile(exe, installed) {
+			fmt.Fprintf(os.Stderr, "warning: %s was not updated; the new version was installed to %s\n", exe, installed)
+		}
+	}
+	return nil
+}
+
+// binaryVersion returns the main module version recorded in the go binary at
+// path.
+func binaryVersion(path string) (string, error) {
+	out, err := exec.Command(goBin(), "version", "-m", path).Output()
+	if err != nil {
+		return "", fmt.Errorf("go version -m %s: %w", path, err)
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[0] == "mod" {
+			return fields[2], nil
+		}
+	}
+	return "", fmt.Errorf("no module version recorded in %s", path)
+}
+
+// exeSuffix returns the file name suffix of executables on this platform.
+func exeSuffix() string {
+	if runtime.GOOS == "windows" {
+		return ".exe"
+	}
+	return ""
+}
+
+// sameFile reports whether the paths a and b refer to the same file.
+func sameFile(a, b string) bool {
+	fa, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	fb, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(fa, fb)
+}